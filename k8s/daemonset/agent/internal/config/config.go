@@ -1,16 +1,28 @@
 package config
 
 import (
+	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strconv"
+	"syscall"
 	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"gopkg.in/yaml.v3"
 )
 
 // Config represents the complete configuration for the KillKrill agent
 type Config struct {
+	// APIVersion identifies which version of this config's schema a file
+	// was written against, e.g. "config/v1". loadConfigFile migrates
+	// older versions (or a file with no apiVersion field at all) up to
+	// CurrentConfigAPIVersion before this struct is populated, so
+	// everything past that point can assume the current shape.
+	APIVersion string `yaml:"apiVersion,omitempty"`
+
 	Agent       AgentConfig       `yaml:"agent"`
 	Logs        LogsConfig        `yaml:"logs"`
 	Metrics     MetricsConfig     `yaml:"metrics"`
@@ -18,6 +30,74 @@ type Config struct {
 	Security    SecurityConfig    `yaml:"security"`
 	Performance PerformanceConfig `yaml:"performance"`
 	Logging     LoggingConfig     `yaml:"logging"`
+
+	// Secrets configures where the license key, output TLS material, and
+	// any extra Logs/Metrics output headers are resolved from. Unset
+	// (the default) leaves those values exactly as applyEnvOverrides and
+	// the output config already set them.
+	Secrets SecretsConfig `yaml:"secrets"`
+}
+
+// SecretsConfig selects, per secret, where its value is resolved from -
+// a plain env var, a file, a Kubernetes Secret mounted via the downward
+// API, or HashiCorp Vault - instead of always reading a fixed environment
+// variable or static config value. Each configured source is re-resolved
+// independently, so e.g. the license key can come from Vault while TLS
+// certs stay file-based.
+type SecretsConfig struct {
+	License SecretSourceConfig            `yaml:"license"`
+	TLS     SecretSourceConfig            `yaml:"tls"`
+	Headers map[string]SecretSourceConfig `yaml:"headers"`
+}
+
+// SecretSourceConfig selects and configures where a single secret value
+// comes from. Source selects which of the fields below is active: "env"
+// (default), "file", "k8s" (a Secret mounted into the pod via the
+// downward API), or "vault".
+type SecretSourceConfig struct {
+	Source string `yaml:"source"`
+
+	Env   SecretEnvConfig   `yaml:"env"`
+	File  SecretFileConfig  `yaml:"file"`
+	K8s   SecretK8sConfig   `yaml:"k8s"`
+	Vault SecretVaultConfig `yaml:"vault"`
+}
+
+// SecretEnvConfig reads the secret from an environment variable.
+type SecretEnvConfig struct {
+	Key string `yaml:"key"`
+}
+
+// SecretFileConfig reads the secret from a file, trimming surrounding
+// whitespace - the same convention OutputAuthConfig's token/password
+// files already use.
+type SecretFileConfig struct {
+	Path string `yaml:"path"`
+}
+
+// SecretK8sConfig reads the secret from a Kubernetes Secret mounted into
+// the pod (e.g. via a projected volume), rather than talking to the
+// apiserver directly - the same downward-API pattern
+// OutputAuthConfig.ServiceAccountTokenFile already relies on. MountPath
+// is the directory the Secret is mounted at; a Secret's keys become file
+// names within it.
+type SecretK8sConfig struct {
+	MountPath string `yaml:"mount_path"`
+	Key       string `yaml:"key"`
+}
+
+// SecretVaultConfig reads the secret from a HashiCorp Vault KV v2 secret.
+// The agent resolves it at startup and again on each config reload
+// (SIGHUP or a change to the config file), so a rotated lease is picked up
+// on the next reload; nothing re-reads Vault on a timer. RefreshInterval
+// (default 5m) is how long a single provider reuses a value it has
+// already read.
+type SecretVaultConfig struct {
+	Address         string `yaml:"address"`
+	Path            string `yaml:"path"`
+	Key             string `yaml:"key"`
+	TokenFile       string `yaml:"token_file"`
+	RefreshInterval string `yaml:"refresh_interval"`
 }
 
 // AgentConfig contains basic agent configuration
@@ -25,25 +105,34 @@ type AgentConfig struct {
 	NodeName    string `yaml:"node_name"`
 	NodeIP      string `yaml:"node_ip"`
 	ClusterName string `yaml:"cluster_name"`
+
+	// ShutdownTimeout bounds how long main waits for collectors and the
+	// health server to stop once shutdown begins, before it moves on to
+	// draining senders regardless.
+	ShutdownTimeout string `yaml:"shutdown_timeout"`
+	// DrainTimeout bounds phase 1 of sender shutdown: flushing whatever
+	// is still queued in a sender's buffer once it stops accepting new
+	// Send() calls.
+	DrainTimeout string `yaml:"drain_timeout"`
 }
 
 // LogsConfig contains log collection configuration
 type LogsConfig struct {
-	Enabled           bool                     `yaml:"enabled"`
-	Paths             []string                 `yaml:"paths"`
-	ContainerRuntimes []string                 `yaml:"container_runtimes"`
-	Parsers           []ParserConfig           `yaml:"parsers"`
-	Kubernetes        KubernetesEnrichConfig   `yaml:"kubernetes"`
-	Output            OutputConfig             `yaml:"output"`
+	Enabled           bool                   `yaml:"enabled"`
+	Paths             []string               `yaml:"paths"`
+	ContainerRuntimes []string               `yaml:"container_runtimes"`
+	Parsers           []ParserConfig         `yaml:"parsers"`
+	Kubernetes        KubernetesEnrichConfig `yaml:"kubernetes"`
+	Output            OutputConfig           `yaml:"output"`
 }
 
 // MetricsConfig contains metrics collection configuration
 type MetricsConfig struct {
-	Enabled    bool                     `yaml:"enabled"`
-	Interval   string                   `yaml:"interval"`
-	Sources    []MetricSourceConfig     `yaml:"sources"`
-	Kubernetes KubernetesEnrichConfig   `yaml:"kubernetes"`
-	Output     OutputConfig             `yaml:"output"`
+	Enabled    bool                   `yaml:"enabled"`
+	Interval   string                 `yaml:"interval"`
+	Sources    []MetricSourceConfig   `yaml:"sources"`
+	Kubernetes KubernetesEnrichConfig `yaml:"kubernetes"`
+	Output     OutputConfig           `yaml:"output"`
 }
 
 // ParserConfig defines how to parse logs
@@ -54,7 +143,11 @@ type ParserConfig struct {
 	TimeFormat string `yaml:"time_format"`
 }
 
-// MetricSourceConfig defines a metrics source
+// MetricSourceConfig defines a metrics source to scrape, in the same
+// terms as a Prometheus scrape_config: either a single static URL, or a
+// KubernetesSDConfigs-driven set of targets discovered per node, with
+// RelabelConfigs/MetricRelabelConfigs applied the same way Prometheus
+// applies them (before and after scraping, respectively).
 type MetricSourceConfig struct {
 	Name     string `yaml:"name"`
 	URL      string `yaml:"url"`
@@ -62,28 +155,202 @@ type MetricSourceConfig struct {
 	KeyFile  string `yaml:"key_file"`
 	CAFile   string `yaml:"ca_file"`
 	Optional bool   `yaml:"optional"`
+
+	// ScrapeInterval and ScrapeTimeout default to the parent
+	// MetricsConfig.Interval and 10s respectively when empty.
+	ScrapeInterval string `yaml:"scrape_interval"`
+	ScrapeTimeout  string `yaml:"scrape_timeout"`
+
+	// MetricsPath defaults to "/metrics".
+	MetricsPath string `yaml:"metrics_path"`
+
+	// HonorLabels, like Prometheus, resolves a label collision between
+	// the scraped series and discovery-provided labels in favor of the
+	// scraped series' own label instead of overwriting it.
+	HonorLabels bool `yaml:"honor_labels"`
+
+	// Params adds query string parameters to every scrape request.
+	Params map[string][]string `yaml:"params"`
+
+	BasicAuth       BasicAuthConfig `yaml:"basic_auth"`
+	BearerTokenFile string          `yaml:"bearer_token_file"`
+
+	// KubernetesSDConfigs discovers scrape targets dynamically instead
+	// of (or alongside) URL, mirroring Prometheus's kubernetes_sd_config.
+	KubernetesSDConfigs []KubernetesSDConfig `yaml:"kubernetes_sd_configs"`
+
+	// RelabelConfigs runs before scraping and can rewrite or drop a
+	// discovered target entirely (e.g. keep only pods with a given
+	// annotation). MetricRelabelConfigs runs after scraping, against
+	// each scraped series' own labels, and is the usual place to drop
+	// noisy series before they're shipped.
+	RelabelConfigs       []RelabelConfig `yaml:"relabel_configs"`
+	MetricRelabelConfigs []RelabelConfig `yaml:"metric_relabel_configs"`
+}
+
+// KubernetesSDConfig is the schema for discovering scrape targets from the
+// Kubernetes API, mirroring Prometheus's kubernetes_sd_config. Role selects
+// what kind of object becomes a target: "pod", "node", or "endpoints".
+//
+// This agent has no Kubernetes informer/client implementing the discovery
+// side yet, only this config shape and validateMetricSource rejecting it
+// as not-yet-implemented - a MetricSourceConfig must use a static URL
+// until that lands.
+type KubernetesSDConfig struct {
+	Role string `yaml:"role"`
+
+	// Namespaces restricts discovery to the named namespaces; empty
+	// discovers across the whole cluster.
+	Namespaces []string `yaml:"namespaces"`
+}
+
+// RelabelConfig is one relabeling rule, applied in the same terms as
+// Prometheus's relabel_config: SourceLabels are joined with Separator
+// (default ";") and matched against Regex; what happens on a match (or
+// non-match, for "keep") depends on Action.
+type RelabelConfig struct {
+	SourceLabels []string `yaml:"source_labels"`
+	Separator    string   `yaml:"separator"`
+	Regex        string   `yaml:"regex"`
+	TargetLabel  string   `yaml:"target_label"`
+	Replacement  string   `yaml:"replacement"`
+	Modulus      uint64   `yaml:"modulus"`
+
+	// Action is one of "keep", "drop", "replace", "labelmap", or
+	// "hashmod". Defaults to "replace".
+	Action string `yaml:"action"`
 }
 
 // KubernetesEnrichConfig contains Kubernetes metadata enrichment configuration
 type KubernetesEnrichConfig struct {
-	Enabled              bool     `yaml:"enabled"`
-	PodMetadata          []string `yaml:"pod_metadata"`
-	NodeMetadata         []string `yaml:"node_metadata"`
-	EnrichPodMetrics     bool     `yaml:"enrich_pod_metrics"`
-	EnrichNodeMetrics    bool     `yaml:"enrich_node_metrics"`
-	EnrichContainerMetrics bool   `yaml:"enrich_container_metrics"`
+	Enabled                bool     `yaml:"enabled"`
+	PodMetadata            []string `yaml:"pod_metadata"`
+	NodeMetadata           []string `yaml:"node_metadata"`
+	EnrichPodMetrics       bool     `yaml:"enrich_pod_metrics"`
+	EnrichNodeMetrics      bool     `yaml:"enrich_node_metrics"`
+	EnrichContainerMetrics bool     `yaml:"enrich_container_metrics"`
 }
 
 // OutputConfig contains output destination configuration
 type OutputConfig struct {
-	Type            string            `yaml:"type"`
-	URL             string            `yaml:"url"`
-	Headers         map[string]string `yaml:"headers"`
-	BatchSize       int               `yaml:"batch_size"`
-	FlushInterval   string            `yaml:"flush_interval"`
-	Compression     string            `yaml:"compression"`
-	RetryAttempts   int               `yaml:"retry_attempts"`
-	RetryBackoff    string            `yaml:"retry_backoff"`
+	Type          string            `yaml:"type"`
+	URL           string            `yaml:"url"`
+	Headers       map[string]string `yaml:"headers"`
+	BatchSize     int               `yaml:"batch_size"`
+	FlushInterval string            `yaml:"flush_interval"`
+	Compression   string            `yaml:"compression"`
+	RetryAttempts int               `yaml:"retry_attempts"`
+	RetryBackoff  string            `yaml:"retry_backoff"`
+
+	// Protocol selects the wire format/serializer used when sending batches.
+	// "killkrill" (default) sends the existing JSON envelope to the py4web
+	// receiver; "otlphttp" sends protobuf-encoded OTLP ExportXServiceRequest
+	// messages to any OTel Collector.
+	Protocol string `yaml:"protocol"`
+
+	// RetryMaxElapsed bounds the total time spent retrying a single batch
+	// (OTLP mode only), after which the batch is dropped rather than retried
+	// forever.
+	RetryMaxElapsed string `yaml:"retry_max_elapsed"`
+
+	// Format selects an alternate payload encoding for metrics outputs.
+	// "" (default) ships the existing JSON envelope; "remote_write" sends
+	// a snappy-compressed Prometheus remote-write protobuf request
+	// straight to Prometheus/Mimir/Thanos/VictoriaMetrics. Ignored for
+	// logs outputs.
+	Format string `yaml:"format"`
+
+	// RemoteWrite configures sharding and multi-tenancy when Format is
+	// "remote_write".
+	RemoteWrite RemoteWriteConfig `yaml:"remote_write"`
+
+	// Buffer configures how outgoing batches are queued before send,
+	// including the on-disk write-ahead log used for backpressure.
+	Buffer BufferConfig `yaml:"buffer"`
+
+	// TLS configures the client TLS material used to connect to this
+	// output, independent of config.SecurityConfig.TLS (which covers the
+	// agent's own inbound health/metrics server).
+	TLS OutputTLSConfig `yaml:"tls"`
+
+	// Auth configures how outbound requests authenticate to this output,
+	// on top of (and taking precedence over) any static Authorization
+	// header set in Headers.
+	Auth OutputAuthConfig `yaml:"auth"`
+}
+
+// OutputTLSConfig configures the TLS material a sender uses to dial its
+// output. Files are watched on disk so a renewed cert/CA is picked up
+// without an agent restart.
+type OutputTLSConfig struct {
+	CAFile             string `yaml:"ca_file"`
+	CertFile           string `yaml:"cert_file"`
+	KeyFile            string `yaml:"key_file"`
+	ServerName         string `yaml:"server_name"`
+	InsecureSkipVerify bool   `yaml:"insecure_skip_verify"`
+}
+
+// OutputAuthConfig configures how a sender authenticates to its output.
+// Type selects which of the fields below is active: "bearer" (static
+// token file), "basic" (username/password files), or "service_account"
+// (periodically re-read Kubernetes projected ServiceAccount token).
+type OutputAuthConfig struct {
+	Type string `yaml:"type"`
+
+	BearerTokenFile string `yaml:"bearer_token_file"`
+
+	Basic BasicAuthConfig `yaml:"basic"`
+
+	// ServiceAccountTokenFile is re-read on every fsnotify event (the
+	// kubelet rewrites projected tokens in place well before they
+	// expire), defaulting to the standard projected-token mount path.
+	ServiceAccountTokenFile string `yaml:"service_account_token_file"`
+}
+
+// BasicAuthConfig holds file paths for HTTP basic auth credentials, so
+// secrets stay out of the config file itself.
+type BasicAuthConfig struct {
+	UsernameFile string `yaml:"username_file"`
+	PasswordFile string `yaml:"password_file"`
+}
+
+// RemoteWriteConfig configures the Prometheus remote-write output mode.
+type RemoteWriteConfig struct {
+	// Shards is the number of per-tenant send queues, each with its own
+	// batch/flush/backoff loop. Series are routed to a shard by hashing
+	// their name+labels, so the same series always lands on the same
+	// shard and retains sample ordering.
+	Shards int `yaml:"shards"`
+
+	// TenantID, when set, is sent as the X-Scope-OrgID header for
+	// Mimir-style multi-tenancy. Empty omits the header.
+	TenantID string `yaml:"tenant_id"`
+}
+
+// BufferConfig configures the send buffer subsystem. When Type is "disk",
+// messages that can't be sent immediately spill to a segmented WAL under
+// base_dir instead of being dropped.
+type BufferConfig struct {
+	// Type selects the buffer implementation: "memory" (default) or "disk".
+	Type string `yaml:"type"`
+
+	// MaxBytes caps the on-disk WAL directory size when Type is "disk", in
+	// Kubernetes Quantity-style notation (e.g. "512Mi"). Zero is unbounded.
+	// The memory buffer ignores it: its capacity is twice batch_size
+	// messages.
+	MaxBytes Quantity `yaml:"max_bytes"`
+
+	// OnFull selects the behavior once the buffer is full:
+	// "drop_newest" (default), "drop_oldest", or "block". A disk buffer
+	// at MaxBytes rejects the incoming message for both "drop_newest" and
+	// "drop_oldest" (records already in the WAL are never discarded), and
+	// for "block" keeps appending past MaxBytes.
+	OnFull string `yaml:"on_full"`
+
+	// BaseDir is the directory for on-disk WAL segments when Type is
+	// "disk". Defaults to /var/lib/killkrill/wal/logs for the logs output
+	// and /var/lib/killkrill/wal/metrics for the metrics output.
+	BaseDir string `yaml:"base_dir"`
 }
 
 // HealthConfig contains health server configuration
@@ -112,13 +379,13 @@ type TLSConfig struct {
 
 // PerformanceConfig contains performance tuning settings
 type PerformanceConfig struct {
-	WorkerThreads       int    `yaml:"worker_threads"`
-	BufferSize          string `yaml:"buffer_size"`
-	QueueSize           int    `yaml:"queue_size"`
-	CompressionLevel    int    `yaml:"compression_level"`
-	BatchTimeout        string `yaml:"batch_timeout"`
-	ConnectionPoolSize  int    `yaml:"connection_pool_size"`
-	KeepAliveTimeout    string `yaml:"keep_alive_timeout"`
+	WorkerThreads      int      `yaml:"worker_threads"`
+	BufferSize         Quantity `yaml:"buffer_size"`
+	QueueSize          int      `yaml:"queue_size"`
+	CompressionLevel   int      `yaml:"compression_level"`
+	BatchTimeout       string   `yaml:"batch_timeout"`
+	ConnectionPoolSize int      `yaml:"connection_pool_size"`
+	KeepAliveTimeout   string   `yaml:"keep_alive_timeout"`
 }
 
 // LoggingConfig contains logging configuration
@@ -131,12 +398,101 @@ type LoggingConfig struct {
 
 // Load loads configuration from environment variables and config file
 func Load() (*Config, error) {
+	return loadFrom(getEnv("CONFIG_FILE", "/etc/killkrill/config.yaml"))
+}
+
+// Watch re-reads configFile on SIGHUP or whenever it changes on disk,
+// re-applying environment overrides and validation the same way Load does,
+// and emits each successfully reloaded *Config on the returned channel.
+// Subsystems that need to reconfigure without a restart - log tailers,
+// metric scrapers, the HTTP/3 output pool - should range over the channel
+// and swap in the new Config; this is critical for daemonset rollouts,
+// where updating a ConfigMap should not restart every node's agent.
+//
+// A reload that fails to load or validate is skipped silently (its error
+// is not reported to the caller) rather than sent on the channel or stopping the
+// watch, so a bad ConfigMap update can't take down a subsystem that's
+// already running on a good config. The channel is closed once ctx is
+// done.
+func Watch(ctx context.Context, configFile string) (<-chan *Config, error) {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create config file watcher: %w", err)
+	}
+
+	dir := filepath.Dir(configFile)
+	if err := watcher.Add(dir); err != nil {
+		watcher.Close()
+		return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+	}
+
+	sighup := make(chan os.Signal, 1)
+	signal.Notify(sighup, syscall.SIGHUP)
+
+	out := make(chan *Config)
+
+	go func() {
+		defer watcher.Close()
+		defer signal.Stop(sighup)
+		defer close(out)
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+
+			case _, ok := <-sighup:
+				if !ok {
+					return
+				}
+				emitReload(configFile, out)
+
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) == 0 {
+					continue
+				}
+				if filepath.Base(event.Name) != filepath.Base(configFile) {
+					continue
+				}
+				emitReload(configFile, out)
+
+			case _, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+			}
+		}
+	}()
+
+	return out, nil
+}
+
+// emitReload re-loads configFile and sends the result on out, skipping the
+// send entirely if the reload fails.
+func emitReload(configFile string, out chan<- *Config) {
+	cfg, err := loadFrom(configFile)
+	if err != nil {
+		return
+	}
+	out <- cfg
+}
+
+// loadFrom builds the default configuration, layers configFile and
+// environment overrides on top if present, and validates the result. Load
+// and Watch both funnel through this so a reload behaves identically to
+// the initial load.
+func loadFrom(configFile string) (*Config, error) {
 	// Default configuration
 	cfg := &Config{
 		Agent: AgentConfig{
-			NodeName:    getEnv("NODE_NAME", "unknown"),
-			NodeIP:      getEnv("NODE_IP", "unknown"),
-			ClusterName: getEnv("CLUSTER_NAME", "default"),
+			NodeName:        getEnv("NODE_NAME", "unknown"),
+			NodeIP:          getEnv("NODE_IP", "unknown"),
+			ClusterName:     getEnv("CLUSTER_NAME", "default"),
+			ShutdownTimeout: getEnv("SHUTDOWN_TIMEOUT", "30s"),
+			DrainTimeout:    getEnv("DRAIN_TIMEOUT", "10s"),
 		},
 		Logs: LogsConfig{
 			Enabled: getBoolEnv("LOGS_ENABLED", true),
@@ -146,26 +502,79 @@ func Load() (*Config, error) {
 			},
 			ContainerRuntimes: []string{"docker", "containerd", "cri-o"},
 			Output: OutputConfig{
-				Type:          "http3",
-				URL:           getEnv("KILLKRILL_LOG_RECEIVER_URL", ""),
-				BatchSize:     getIntEnv("LOG_BATCH_SIZE", 1000),
-				FlushInterval: getEnv("LOG_FLUSH_INTERVAL", "5s"),
-				Compression:   "gzip",
-				RetryAttempts: 3,
-				RetryBackoff:  "1s",
+				Type:            "http3",
+				URL:             getEnv("KILLKRILL_LOG_RECEIVER_URL", ""),
+				BatchSize:       getIntEnv("LOG_BATCH_SIZE", 1000),
+				FlushInterval:   getEnv("LOG_FLUSH_INTERVAL", "5s"),
+				Compression:     "gzip",
+				RetryAttempts:   3,
+				RetryBackoff:    "1s",
+				Protocol:        getEnv("LOG_OUTPUT_PROTOCOL", "killkrill"),
+				RetryMaxElapsed: getEnv("LOG_OUTPUT_RETRY_MAX_ELAPSED", "5m"),
+				Buffer: BufferConfig{
+					Type:     getEnv("LOG_OUTPUT_BUFFER_TYPE", "memory"),
+					MaxBytes: getQuantityEnv("LOG_OUTPUT_BUFFER_MAX_BYTES", "256Mi"),
+					OnFull:   getEnv("LOG_OUTPUT_BUFFER_ON_FULL", "drop_newest"),
+					BaseDir:  getEnv("LOG_OUTPUT_BUFFER_BASE_DIR", "/var/lib/killkrill/wal/logs"),
+				},
+				TLS: OutputTLSConfig{
+					CAFile:             getEnv("LOG_OUTPUT_TLS_CA_FILE", ""),
+					CertFile:           getEnv("LOG_OUTPUT_TLS_CERT_FILE", ""),
+					KeyFile:            getEnv("LOG_OUTPUT_TLS_KEY_FILE", ""),
+					ServerName:         getEnv("LOG_OUTPUT_TLS_SERVER_NAME", ""),
+					InsecureSkipVerify: getBoolEnv("LOG_OUTPUT_TLS_INSECURE_SKIP_VERIFY", false),
+				},
+				Auth: OutputAuthConfig{
+					Type:            getEnv("LOG_OUTPUT_AUTH_TYPE", ""),
+					BearerTokenFile: getEnv("LOG_OUTPUT_AUTH_BEARER_TOKEN_FILE", ""),
+					Basic: BasicAuthConfig{
+						UsernameFile: getEnv("LOG_OUTPUT_AUTH_BASIC_USERNAME_FILE", ""),
+						PasswordFile: getEnv("LOG_OUTPUT_AUTH_BASIC_PASSWORD_FILE", ""),
+					},
+					ServiceAccountTokenFile: getEnv("LOG_OUTPUT_AUTH_SERVICE_ACCOUNT_TOKEN_FILE", "/var/run/secrets/tokens/killkrill"),
+				},
 			},
 		},
 		Metrics: MetricsConfig{
 			Enabled:  getBoolEnv("METRICS_ENABLED", true),
 			Interval: getEnv("METRICS_INTERVAL", "30s"),
 			Output: OutputConfig{
-				Type:          "http3",
-				URL:           getEnv("KILLKRILL_METRICS_RECEIVER_URL", ""),
-				BatchSize:     getIntEnv("METRICS_BATCH_SIZE", 500),
-				FlushInterval: getEnv("METRICS_FLUSH_INTERVAL", "30s"),
-				Compression:   "gzip",
-				RetryAttempts: 3,
-				RetryBackoff:  "2s",
+				Type:            "http3",
+				URL:             getEnv("KILLKRILL_METRICS_RECEIVER_URL", ""),
+				BatchSize:       getIntEnv("METRICS_BATCH_SIZE", 500),
+				FlushInterval:   getEnv("METRICS_FLUSH_INTERVAL", "30s"),
+				Compression:     "gzip",
+				RetryAttempts:   3,
+				RetryBackoff:    "2s",
+				Protocol:        getEnv("METRICS_OUTPUT_PROTOCOL", "killkrill"),
+				RetryMaxElapsed: getEnv("METRICS_OUTPUT_RETRY_MAX_ELAPSED", "5m"),
+				Buffer: BufferConfig{
+					Type:     getEnv("METRICS_OUTPUT_BUFFER_TYPE", "memory"),
+					MaxBytes: getQuantityEnv("METRICS_OUTPUT_BUFFER_MAX_BYTES", "256Mi"),
+					OnFull:   getEnv("METRICS_OUTPUT_BUFFER_ON_FULL", "drop_newest"),
+					BaseDir:  getEnv("METRICS_OUTPUT_BUFFER_BASE_DIR", "/var/lib/killkrill/wal/metrics"),
+				},
+				TLS: OutputTLSConfig{
+					CAFile:             getEnv("METRICS_OUTPUT_TLS_CA_FILE", ""),
+					CertFile:           getEnv("METRICS_OUTPUT_TLS_CERT_FILE", ""),
+					KeyFile:            getEnv("METRICS_OUTPUT_TLS_KEY_FILE", ""),
+					ServerName:         getEnv("METRICS_OUTPUT_TLS_SERVER_NAME", ""),
+					InsecureSkipVerify: getBoolEnv("METRICS_OUTPUT_TLS_INSECURE_SKIP_VERIFY", false),
+				},
+				Auth: OutputAuthConfig{
+					Type:            getEnv("METRICS_OUTPUT_AUTH_TYPE", ""),
+					BearerTokenFile: getEnv("METRICS_OUTPUT_AUTH_BEARER_TOKEN_FILE", ""),
+					Basic: BasicAuthConfig{
+						UsernameFile: getEnv("METRICS_OUTPUT_AUTH_BASIC_USERNAME_FILE", ""),
+						PasswordFile: getEnv("METRICS_OUTPUT_AUTH_BASIC_PASSWORD_FILE", ""),
+					},
+					ServiceAccountTokenFile: getEnv("METRICS_OUTPUT_AUTH_SERVICE_ACCOUNT_TOKEN_FILE", "/var/run/secrets/tokens/killkrill"),
+				},
+				Format: getEnv("METRICS_OUTPUT_FORMAT", ""),
+				RemoteWrite: RemoteWriteConfig{
+					Shards:   getIntEnv("METRICS_OUTPUT_REMOTE_WRITE_SHARDS", 4),
+					TenantID: getEnv("METRICS_OUTPUT_REMOTE_WRITE_TENANT_ID", ""),
+				},
 			},
 		},
 		Health: HealthConfig{
@@ -183,7 +592,7 @@ func Load() (*Config, error) {
 		},
 		Performance: PerformanceConfig{
 			WorkerThreads:      getIntEnv("WORKER_THREADS", 4),
-			BufferSize:         getEnv("BUFFER_SIZE", "16MB"),
+			BufferSize:         getQuantityEnv("BUFFER_SIZE", "16Mi"),
 			QueueSize:          getIntEnv("QUEUE_SIZE", 10000),
 			CompressionLevel:   getIntEnv("COMPRESSION_LEVEL", 6),
 			BatchTimeout:       getEnv("BATCH_TIMEOUT", "1s"),
@@ -204,7 +613,6 @@ func Load() (*Config, error) {
 	}
 
 	// Load additional configuration from file if it exists
-	configFile := getEnv("CONFIG_FILE", "/etc/killkrill/config.yaml")
 	if _, err := os.Stat(configFile); err == nil {
 		if err := loadConfigFile(cfg, configFile); err != nil {
 			return nil, fmt.Errorf("failed to load config file %s: %w", configFile, err)
@@ -224,6 +632,13 @@ func Load() (*Config, error) {
 	return cfg, nil
 }
 
+// loadConfigFile parses filename, migrates it from whatever apiVersion it
+// declares (or legacyConfigAPIVersion, if it declares none) up to
+// CurrentConfigAPIVersion, validates the migrated document's schema, and
+// only then decodes it into cfg - so a malformed field is reported with
+// its exact location ("logs.output.batch_size: expected integer, got
+// string at line 14") instead of silently decoding to a zero value or
+// failing validate() with a much less specific error downstream.
 func loadConfigFile(cfg *Config, filename string) error {
 	data, err := os.ReadFile(filename)
 	if err != nil {
@@ -233,7 +648,21 @@ func loadConfigFile(cfg *Config, filename string) error {
 	// Expand environment variables in config file
 	expanded := os.ExpandEnv(string(data))
 
-	return yaml.Unmarshal([]byte(expanded), cfg)
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(expanded), &doc); err != nil {
+		return fmt.Errorf("failed to parse %s: %w", filename, err)
+	}
+
+	migrated, err := migrateDoc(&doc)
+	if err != nil {
+		return fmt.Errorf("failed to migrate %s: %w", filename, err)
+	}
+
+	if err := ValidateSchema(migrated); err != nil {
+		return fmt.Errorf("%s failed schema validation: %w", filename, err)
+	}
+
+	return migrated.Decode(cfg)
 }
 
 func applyEnvOverrides(cfg *Config) error {
@@ -266,6 +695,14 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("agent.cluster_name is required")
 	}
 
+	if _, err := time.ParseDuration(cfg.Agent.ShutdownTimeout); err != nil {
+		return fmt.Errorf("invalid agent.shutdown_timeout: %w", err)
+	}
+
+	if _, err := time.ParseDuration(cfg.Agent.DrainTimeout); err != nil {
+		return fmt.Errorf("invalid agent.drain_timeout: %w", err)
+	}
+
 	if cfg.Logs.Enabled && cfg.Logs.Output.URL == "" {
 		return fmt.Errorf("logs.output.url is required when logs are enabled")
 	}
@@ -289,6 +726,243 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("invalid metrics.output.flush_interval: %w", err)
 	}
 
+	if err := validateOutputProtocol(cfg.Logs.Output, "logs"); err != nil {
+		return err
+	}
+
+	if err := validateOutputProtocol(cfg.Metrics.Output, "metrics"); err != nil {
+		return err
+	}
+
+	if err := validateBuffer(cfg.Logs.Output.Buffer, "logs"); err != nil {
+		return err
+	}
+
+	if err := validateBuffer(cfg.Metrics.Output.Buffer, "metrics"); err != nil {
+		return err
+	}
+
+	if err := validateAuth(cfg.Logs.Output.Auth, "logs"); err != nil {
+		return err
+	}
+
+	if err := validateAuth(cfg.Metrics.Output.Auth, "metrics"); err != nil {
+		return err
+	}
+
+	if err := validateMetricsFormat(cfg.Metrics.Output); err != nil {
+		return err
+	}
+
+	if cfg.Performance.BufferSize.Bytes() <= 0 {
+		return fmt.Errorf("performance.buffer_size must be a positive quantity, got %q", cfg.Performance.BufferSize)
+	}
+
+	if err := validateSecrets(cfg.Secrets); err != nil {
+		return err
+	}
+
+	for i, source := range cfg.Metrics.Sources {
+		if err := validateMetricSource(source, fmt.Sprintf("metrics.sources[%d]", i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateMetricSource checks a single MetricSourceConfig's scrape
+// settings: durations parse, kubernetes_sd_configs is not set at all, and
+// relabel_configs/metric_relabel_configs use a known action.
+func validateMetricSource(cfg MetricSourceConfig, path string) error {
+	if cfg.ScrapeInterval != "" {
+		if _, err := time.ParseDuration(cfg.ScrapeInterval); err != nil {
+			return fmt.Errorf("invalid %s.scrape_interval: %w", path, err)
+		}
+	}
+
+	if cfg.ScrapeTimeout != "" {
+		if _, err := time.ParseDuration(cfg.ScrapeTimeout); err != nil {
+			return fmt.Errorf("invalid %s.scrape_timeout: %w", path, err)
+		}
+	}
+
+	// kubernetes_sd_configs is schema-only for now: nothing in this agent
+	// runs a Kubernetes informer/client to actually discover targets from
+	// it, so silently accepting it here would produce a scrape config that
+	// looks like it's doing dynamic discovery but never finds any targets.
+	// Reject it up front instead until real discovery is implemented,
+	// rather than validating sd.Role against a feature that can't run.
+	if len(cfg.KubernetesSDConfigs) > 0 {
+		return fmt.Errorf("%s.kubernetes_sd_configs is not yet implemented: this agent has no Kubernetes discovery client, use a static url instead", path)
+	}
+
+	for i, rule := range cfg.RelabelConfigs {
+		if err := validateRelabelConfig(rule, fmt.Sprintf("%s.relabel_configs[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	for i, rule := range cfg.MetricRelabelConfigs {
+		if err := validateRelabelConfig(rule, fmt.Sprintf("%s.metric_relabel_configs[%d]", path, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateRelabelConfig checks a single RelabelConfig's action and the
+// fields it requires, mirroring Prometheus's own relabel_config rules
+// (e.g. hashmod requires a modulus and a target_label to write the hash
+// into).
+func validateRelabelConfig(cfg RelabelConfig, path string) error {
+	switch cfg.Action {
+	case "", "replace", "keep", "drop", "labelmap":
+	case "hashmod":
+		if cfg.Modulus == 0 {
+			return fmt.Errorf("%s.modulus must be positive when action is hashmod", path)
+		}
+		if cfg.TargetLabel == "" {
+			return fmt.Errorf("%s.target_label is required when action is hashmod", path)
+		}
+	default:
+		return fmt.Errorf("%s.action must be one of keep|drop|replace|labelmap|hashmod, got %q", path, cfg.Action)
+	}
+
+	return nil
+}
+
+// validateSecrets checks that any configured secret sources are well
+// formed. Resolving them (reading the env var/file/Vault secret) happens
+// later, in secrets.ApplyOverrides, since that can fail for reasons
+// outside the config itself (Vault unreachable, file not yet mounted).
+func validateSecrets(cfg SecretsConfig) error {
+	if err := validateSecretSource(cfg.License, "secrets.license"); err != nil {
+		return err
+	}
+
+	if cfg.TLS.Source != "" {
+		switch cfg.TLS.Source {
+		case "file", "k8s":
+		default:
+			return fmt.Errorf("secrets.tls.source must be one of file|k8s, got %q", cfg.TLS.Source)
+		}
+	}
+
+	for name, src := range cfg.Headers {
+		if err := validateSecretSource(src, fmt.Sprintf("secrets.headers[%s]", name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateSecretSource checks a single-value SecretSourceConfig (the
+// license key or an output header), used by every caller except
+// secrets.tls, which is directory-based and validated separately.
+func validateSecretSource(cfg SecretSourceConfig, path string) error {
+	switch cfg.Source {
+	case "", "env", "file", "k8s":
+	case "vault":
+		if cfg.Vault.Address == "" || cfg.Vault.Path == "" || cfg.Vault.Key == "" {
+			return fmt.Errorf("%s.vault requires address, path and key", path)
+		}
+		if cfg.Vault.RefreshInterval != "" {
+			if _, err := time.ParseDuration(cfg.Vault.RefreshInterval); err != nil {
+				return fmt.Errorf("invalid %s.vault.refresh_interval: %w", path, err)
+			}
+		}
+	default:
+		return fmt.Errorf("%s.source must be one of env|file|k8s|vault, got %q", path, cfg.Source)
+	}
+
+	return nil
+}
+
+// validateMetricsFormat checks metrics.output.format and, when it selects
+// remote_write, the RemoteWrite shard count.
+func validateMetricsFormat(output OutputConfig) error {
+	switch output.Format {
+	case "", "remote_write":
+	default:
+		return fmt.Errorf("metrics.output.format must be one of \"\"|remote_write, got %q", output.Format)
+	}
+
+	if output.Format == "remote_write" && output.RemoteWrite.Shards <= 0 {
+		return fmt.Errorf("metrics.output.remote_write.shards must be positive, got %d", output.RemoteWrite.Shards)
+	}
+
+	return nil
+}
+
+// validateAuth checks that an OutputAuthConfig is well formed for the given
+// section name (used in error messages, e.g. "logs" or "metrics").
+func validateAuth(auth OutputAuthConfig, section string) error {
+	switch auth.Type {
+	case "":
+	case "bearer":
+		if auth.BearerTokenFile == "" {
+			return fmt.Errorf("%s.output.auth.bearer_token_file is required when auth.type is bearer", section)
+		}
+	case "basic":
+		if auth.Basic.UsernameFile == "" || auth.Basic.PasswordFile == "" {
+			return fmt.Errorf("%s.output.auth.basic requires both username_file and password_file", section)
+		}
+	case "service_account":
+		if auth.ServiceAccountTokenFile == "" {
+			return fmt.Errorf("%s.output.auth.service_account_token_file is required when auth.type is service_account", section)
+		}
+	default:
+		return fmt.Errorf("%s.output.auth.type must be one of bearer|basic|service_account, got %q", section, auth.Type)
+	}
+
+	return nil
+}
+
+// validateOutputProtocol checks that an output's protocol and related
+// settings are well formed for the given section name (used in error
+// messages, e.g. "logs" or "metrics").
+func validateOutputProtocol(output OutputConfig, section string) error {
+	switch output.Protocol {
+	case "", "killkrill":
+		return nil
+	case "otlphttp":
+		if output.RetryMaxElapsed != "" {
+			if _, err := time.ParseDuration(output.RetryMaxElapsed); err != nil {
+				return fmt.Errorf("invalid %s.output.retry_max_elapsed: %w", section, err)
+			}
+		}
+		return nil
+	default:
+		return fmt.Errorf("%s.output.protocol must be one of killkrill|otlphttp, got %q", section, output.Protocol)
+	}
+}
+
+// validateBuffer checks that a BufferConfig is well formed for the given
+// section name (used in error messages, e.g. "logs" or "metrics").
+func validateBuffer(buffer BufferConfig, section string) error {
+	switch buffer.Type {
+	case "", "memory":
+	case "disk":
+		if buffer.BaseDir == "" {
+			return fmt.Errorf("%s.output.buffer.base_dir is required when buffer.type is disk", section)
+		}
+	default:
+		return fmt.Errorf("%s.output.buffer.type must be one of memory|disk, got %q", section, buffer.Type)
+	}
+
+	switch buffer.OnFull {
+	case "", "drop_newest", "drop_oldest", "block":
+	default:
+		return fmt.Errorf("%s.output.buffer.on_full must be one of drop_newest|drop_oldest|block, got %q", section, buffer.OnFull)
+	}
+
+	if buffer.MaxBytes.Bytes() < 0 {
+		return fmt.Errorf("%s.output.buffer.max_bytes must not be negative, got %q", section, buffer.MaxBytes)
+	}
+
 	return nil
 }
 
@@ -316,4 +990,15 @@ func getIntEnv(key string, defaultValue int) int {
 		}
 	}
 	return defaultValue
-}
\ No newline at end of file
+}
+
+// getQuantityEnv reads key as a Kubernetes-style Quantity, falling back to
+// defaultValue (which must itself be valid) if key is unset or unparseable.
+func getQuantityEnv(key, defaultValue string) Quantity {
+	if value := os.Getenv(key); value != "" {
+		if parsed, err := ParseQuantity(value); err == nil {
+			return parsed
+		}
+	}
+	return MustParseQuantity(defaultValue)
+}
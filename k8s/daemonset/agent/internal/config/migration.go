@@ -0,0 +1,112 @@
+package config
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// legacyConfigAPIVersion is assumed for any config file with no
+// apiVersion field, since every file written before this field existed
+// is one.
+const legacyConfigAPIVersion = "config/v1alpha1"
+
+// CurrentConfigAPIVersion is the apiVersion newly written config files
+// should set, and the version every chain in configMigrations upgrades
+// its input to.
+const CurrentConfigAPIVersion = "config/v1"
+
+// configMigrations maps a config apiVersion to the function that
+// upgrades a document at that version to the next one in the chain -
+// mirroring, one version at a time, how a CRD conversion webhook walks a
+// hub-and-spoke chain of ConvertTo/ConvertFrom calls. migrateDoc follows
+// the chain until it reaches CurrentConfigAPIVersion.
+var configMigrations = map[string]func(*yaml.Node) (*yaml.Node, error){
+	legacyConfigAPIVersion: migrateV1alpha1ToV1,
+}
+
+// migrateDoc reads root's apiVersion field (legacyConfigAPIVersion if
+// absent) and walks it through configMigrations up to
+// CurrentConfigAPIVersion, returning the migrated document ready for
+// ValidateSchema and Decode - both of which only understand the current
+// shape.
+func migrateDoc(doc *yaml.Node) (*yaml.Node, error) {
+	root := documentRoot(doc)
+	if root == nil {
+		return doc, nil
+	}
+
+	version := apiVersionOf(root)
+
+	for version != CurrentConfigAPIVersion {
+		migrate, ok := configMigrations[version]
+		if !ok {
+			return nil, fmt.Errorf("unknown config apiVersion %q", version)
+		}
+
+		next, err := migrate(root)
+		if err != nil {
+			return nil, fmt.Errorf("failed to migrate config from %s: %w", version, err)
+		}
+
+		root = next
+		version = apiVersionOf(root)
+	}
+
+	return root, nil
+}
+
+// migrateV1alpha1ToV1 stamps apiVersion: config/v1 onto doc. No field
+// renames are needed yet - v1's shape is identical to v1alpha1's today -
+// so this is the hook a future migration (a renamed or restructured
+// field) attaches to, rather than a no-op chain step to delete.
+func migrateV1alpha1ToV1(doc *yaml.Node) (*yaml.Node, error) {
+	setAPIVersion(doc, CurrentConfigAPIVersion)
+	return doc, nil
+}
+
+// documentRoot unwraps a parsed document down to its top-level mapping
+// node, or nil for an empty document (e.g. an empty config file).
+func documentRoot(doc *yaml.Node) *yaml.Node {
+	root := doc
+	if root.Kind == yaml.DocumentNode {
+		if len(root.Content) == 0 {
+			return nil
+		}
+		root = root.Content[0]
+	}
+	if root.Kind == 0 {
+		return nil
+	}
+	return root
+}
+
+// apiVersionOf reads node's apiVersion field, defaulting to
+// legacyConfigAPIVersion if node isn't a mapping or has no such field.
+func apiVersionOf(node *yaml.Node) string {
+	if node.Kind != yaml.MappingNode {
+		return legacyConfigAPIVersion
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "apiVersion" {
+			return node.Content[i+1].Value
+		}
+	}
+	return legacyConfigAPIVersion
+}
+
+// setAPIVersion sets (or adds) node's apiVersion field to version.
+func setAPIVersion(node *yaml.Node, version string) {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "apiVersion" {
+			node.Content[i+1].Value = version
+			node.Content[i+1].Tag = "!!str"
+			return
+		}
+	}
+
+	node.Content = append(node.Content,
+		&yaml.Node{Kind: yaml.ScalarNode, Value: "apiVersion", Tag: "!!str"},
+		&yaml.Node{Kind: yaml.ScalarNode, Value: version, Tag: "!!str"},
+	)
+}
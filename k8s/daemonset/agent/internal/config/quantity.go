@@ -0,0 +1,112 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Quantity is a Kubernetes-style resource quantity (e.g. "16Mi", "2Gi",
+// "1.5G", "2e6"), parsed once at config load so BufferSize/MaxBytes and
+// similar fields work in bytes everywhere they're used instead of being
+// re-parsed ad hoc, matching what operators already expect from k8s
+// manifests. The zero value is the empty quantity (Bytes() == 0).
+type Quantity struct {
+	raw   string
+	bytes int64
+}
+
+// quantitySuffixes are checked longest-first so "Ki" is matched before a
+// bare "K" would be (even though, since these are full-string suffixes,
+// only one can ever match a given input).
+var quantitySuffixes = []struct {
+	suffix     string
+	multiplier float64
+}{
+	{"Ei", 1 << 60}, {"Pi", 1 << 50}, {"Ti", 1 << 40}, {"Gi", 1 << 30}, {"Mi", 1 << 20}, {"Ki", 1 << 10},
+	{"E", 1e18}, {"P", 1e15}, {"T", 1e12}, {"G", 1e9}, {"M", 1e6}, {"k", 1e3}, {"K", 1e3},
+	{"m", 1e-3},
+}
+
+// ParseQuantity parses a Kubernetes-style quantity string: binary suffixes
+// (Ki/Mi/Gi/Ti/Pi/Ei), decimal SI suffixes (m/k/K/M/G/T/P/E), and plain
+// decimal or decimalExponent numbers (e.g. "1e3"), which Go's ParseFloat
+// already understands. An empty string parses as the zero Quantity.
+func ParseQuantity(s string) (Quantity, error) {
+	if s == "" {
+		return Quantity{}, nil
+	}
+
+	for _, suf := range quantitySuffixes {
+		if !strings.HasSuffix(s, suf.suffix) {
+			continue
+		}
+		numeric := strings.TrimSuffix(s, suf.suffix)
+		value, err := strconv.ParseFloat(numeric, 64)
+		if err != nil {
+			return Quantity{}, fmt.Errorf("invalid quantity %q: %w", s, err)
+		}
+		return Quantity{raw: s, bytes: int64(value * suf.multiplier)}, nil
+	}
+
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return Quantity{}, fmt.Errorf("invalid quantity %q: %w", s, err)
+	}
+	return Quantity{raw: s, bytes: int64(value)}, nil
+}
+
+// MustParseQuantity is ParseQuantity for callers (mainly defaults) that
+// know the string is well formed; it panics otherwise.
+func MustParseQuantity(s string) Quantity {
+	q, err := ParseQuantity(s)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Bytes returns the quantity's value in bytes.
+func (q Quantity) Bytes() int64 { return q.bytes }
+
+// IsZero reports whether the quantity was never set.
+func (q Quantity) IsZero() bool { return q.raw == "" }
+
+// String round-trips the original string this Quantity was parsed from,
+// rather than re-rendering from q.bytes, so e.g. "1Gi" doesn't become
+// "1073741824".
+func (q Quantity) String() string { return q.raw }
+
+// UnmarshalYAML implements yaml.Unmarshaler.
+func (q *Quantity) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	parsed, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (q *Quantity) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := ParseQuantity(s)
+	if err != nil {
+		return err
+	}
+	*q = parsed
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (q Quantity) MarshalJSON() ([]byte, error) {
+	return json.Marshal(q.raw)
+}
@@ -0,0 +1,216 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ValidateSchema walks doc field by field against Config's shape,
+// collecting every type mismatch it finds (rather than stopping at the
+// first) so a bad config file reports everything wrong with it at once,
+// e.g. "logs.output.batch_size: expected integer, got string at line 14".
+// This catches the mismatch before Decode leaves a field at its Go zero
+// value and validate() reports a much less specific error later.
+func ValidateSchema(doc *yaml.Node) error {
+	root := documentRoot(doc)
+	if root == nil {
+		return nil
+	}
+
+	var errs []schemaMismatch
+	walkSchema(root, reflect.TypeOf(Config{}), "", &errs)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	sort.Slice(errs, func(i, j int) bool { return errs[i].line < errs[j].line })
+
+	messages := make([]string, len(errs))
+	for i, e := range errs {
+		messages[i] = e.String()
+	}
+	return fmt.Errorf("config schema validation failed:\n%s", strings.Join(messages, "\n"))
+}
+
+// schemaMismatch is one field whose YAML value's kind doesn't match what
+// its Go field expects.
+type schemaMismatch struct {
+	path     string
+	expected string
+	got      string
+	line     int
+}
+
+func (e schemaMismatch) String() string {
+	return fmt.Sprintf("%s: expected %s, got %s at line %d", e.path, e.expected, e.got, e.line)
+}
+
+// walkSchema checks node against t (a struct, slice, map, or scalar
+// field's reflect.Type), recursing into structs/slices/maps and
+// appending a schemaMismatch for every scalar kind mismatch found.
+func walkSchema(node *yaml.Node, t reflect.Type, path string, errs *[]schemaMismatch) {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	switch {
+	case isOpaqueScalarType(t):
+		// Quantity (and any future type like it) parses and validates
+		// its own raw string in UnmarshalYAML; schema validation just
+		// confirms the YAML value is a plain scalar.
+		expectScalar(node, "string", path, errs)
+
+	case t.Kind() == reflect.Struct:
+		walkStruct(node, t, path, errs)
+
+	case t.Kind() == reflect.Slice || t.Kind() == reflect.Array:
+		if node.Kind != yaml.SequenceNode {
+			*errs = append(*errs, mismatch(node, path, "sequence"))
+			return
+		}
+		for i, item := range node.Content {
+			walkSchema(item, t.Elem(), fmt.Sprintf("%s[%d]", path, i), errs)
+		}
+
+	case t.Kind() == reflect.Map:
+		if node.Kind != yaml.MappingNode {
+			*errs = append(*errs, mismatch(node, path, "mapping"))
+			return
+		}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			walkSchema(node.Content[i+1], t.Elem(), path+"."+key, errs)
+		}
+
+	case t.Kind() == reflect.String:
+		expectScalar(node, "string", path, errs)
+
+	case t.Kind() == reflect.Bool:
+		expectScalar(node, "boolean", path, errs)
+
+	case isIntKind(t.Kind()):
+		expectScalar(node, "integer", path, errs)
+
+	default:
+		// Unhandled kinds (interfaces, funcs, ...) aren't present in
+		// Config today; skip rather than false-positive.
+	}
+}
+
+// walkStruct checks node, which must be a mapping, against t's
+// yaml-tagged fields, recursing into each one present in node. Keys in
+// node with no matching field are left alone - yaml.Unmarshal already
+// ignores them the same way.
+func walkStruct(node *yaml.Node, t reflect.Type, path string, errs *[]schemaMismatch) {
+	if node.Kind != yaml.MappingNode {
+		*errs = append(*errs, mismatch(node, path, "mapping"))
+		return
+	}
+
+	fieldsByTag := make(map[string]reflect.StructField, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("yaml")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		fieldsByTag[strings.Split(tag, ",")[0]] = field
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		field, ok := fieldsByTag[key]
+		if !ok {
+			continue
+		}
+
+		childPath := key
+		if path != "" {
+			childPath = path + "." + key
+		}
+		walkSchema(node.Content[i+1], field.Type, childPath, errs)
+	}
+}
+
+// expectScalar checks that node is a plain scalar whose YAML-resolved
+// kind is compatible with expected.
+func expectScalar(node *yaml.Node, expected string, path string, errs *[]schemaMismatch) {
+	if node.Kind != yaml.ScalarNode {
+		*errs = append(*errs, mismatch(node, path, expected))
+		return
+	}
+
+	got := scalarKind(node)
+	if !scalarCompatible(expected, got) {
+		*errs = append(*errs, schemaMismatch{path: path, expected: expected, got: got, line: node.Line})
+	}
+}
+
+// scalarKind classifies a scalar node the way YAML's own resolver
+// already has, via the tag it assigned during parsing.
+func scalarKind(node *yaml.Node) string {
+	switch node.Tag {
+	case "!!int":
+		return "integer"
+	case "!!bool":
+		return "boolean"
+	case "!!float":
+		return "float"
+	case "!!null":
+		return "null"
+	default:
+		return "string"
+	}
+}
+
+// scalarCompatible reports whether a YAML scalar of kind got can be
+// decoded into a Go field expecting kind expected. A bare number or
+// boolean is also accepted where a string field is expected, since
+// that's a valid (if unquoted) YAML string and gopkg.in/yaml.v3 decodes
+// it as such; the case this actually exists to catch is the reverse - a
+// quoted string where an int/bool field expects a real scalar - plus
+// null.
+func scalarCompatible(expected, got string) bool {
+	if expected == got {
+		return true
+	}
+	if expected == "string" {
+		return got != "null"
+	}
+	return false
+}
+
+func mismatch(node *yaml.Node, path, expected string) schemaMismatch {
+	return schemaMismatch{path: path, expected: expected, got: nodeKindName(node), line: node.Line}
+}
+
+func nodeKindName(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "mapping"
+	case yaml.SequenceNode:
+		return "sequence"
+	case yaml.ScalarNode:
+		return scalarKind(node)
+	default:
+		return "unknown"
+	}
+}
+
+func isOpaqueScalarType(t reflect.Type) bool {
+	return t == reflect.TypeOf(Quantity{})
+}
+
+func isIntKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
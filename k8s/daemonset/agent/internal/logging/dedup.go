@@ -0,0 +1,182 @@
+package logging
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// defaultDedupWindow is how long identical records are suppressed before a
+// summary record is emitted, unless the caller configures a different one.
+const defaultDedupWindow = 10 * time.Second
+
+// defaultDedupCacheSize bounds the LRU of record keys tracked at once, so a
+// logger emitting many distinct messages doesn't grow this unbounded.
+const defaultDedupCacheSize = 1024
+
+// dedupEntry tracks a suppressed run of identical records.
+type dedupEntry struct {
+	key   string
+	count int
+	first time.Time
+	last  time.Time
+	level slog.Level
+	msg   string
+	attrs []slog.Attr
+}
+
+// DedupHandler wraps a slog.Handler and suppresses repeated identical
+// records (same level + message + attrs) within a window, emitting a single
+// summary record ("count=N, first=..., last=...") once the window closes.
+// This prevents a stuck receiver from producing gigabytes of identical
+// "send failed" log lines.
+type DedupHandler struct {
+	next   slog.Handler
+	window time.Duration
+
+	// mu guards entries/order. WithAttrs/WithGroup derive a handler that
+	// shares this same mutex (not a fresh zero-value one) along with
+	// entries/order, since the point of sharing those is letting every
+	// sender - each of which calls With... once at construction and then
+	// uses its own handler - dedup against the same state; a private
+	// mutex per derived handler would let concurrent senders race on that
+	// shared map/list instead of actually serializing access to it.
+	mu      *sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List
+	maxSize int
+}
+
+// NewDedupHandler wraps next, suppressing duplicate records within window.
+// A window of 0 uses defaultDedupWindow.
+func NewDedupHandler(next slog.Handler, window time.Duration) *DedupHandler {
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+
+	h := &DedupHandler{
+		next:    next,
+		window:  window,
+		mu:      &sync.Mutex{},
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+		maxSize: defaultDedupCacheSize,
+	}
+
+	go h.sweepLoop()
+
+	return h
+}
+
+func (h *DedupHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h *DedupHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &DedupHandler{next: h.next.WithAttrs(attrs), window: h.window, mu: h.mu, entries: h.entries, order: h.order, maxSize: h.maxSize}
+}
+
+func (h *DedupHandler) WithGroup(name string) slog.Handler {
+	return &DedupHandler{next: h.next.WithGroup(name), window: h.window, mu: h.mu, entries: h.entries, order: h.order, maxSize: h.maxSize}
+}
+
+// Handle suppresses a record if an identical one (by level+message+attrs
+// hash) was already seen within the current window, otherwise passes it
+// through and starts tracking it.
+func (h *DedupHandler) Handle(ctx context.Context, record slog.Record) error {
+	var attrs []slog.Attr
+	record.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, a)
+		return true
+	})
+
+	key := recordKey(record.Level, record.Message, attrs)
+
+	h.mu.Lock()
+	if el, ok := h.entries[key]; ok {
+		entry := el.Value.(*dedupEntry)
+		entry.count++
+		entry.last = record.Time
+		h.order.MoveToFront(el)
+		h.mu.Unlock()
+		return nil
+	}
+
+	entry := &dedupEntry{key: key, count: 1, first: record.Time, last: record.Time, level: record.Level, msg: record.Message, attrs: attrs}
+	el := h.order.PushFront(entry)
+	h.entries[key] = el
+
+	if h.order.Len() > h.maxSize {
+		oldest := h.order.Back()
+		if oldest != nil {
+			h.order.Remove(oldest)
+			delete(h.entries, oldest.Value.(*dedupEntry).key)
+		}
+	}
+	h.mu.Unlock()
+
+	return h.next.Handle(ctx, record)
+}
+
+// sweepLoop periodically flushes entries whose window has closed, emitting
+// a summary record for any that had at least one repeat suppressed.
+func (h *DedupHandler) sweepLoop() {
+	ticker := time.NewTicker(h.window)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		h.flushExpired()
+	}
+}
+
+func (h *DedupHandler) flushExpired() {
+	now := time.Now()
+
+	h.mu.Lock()
+	var expired []*dedupEntry
+	for el := h.order.Back(); el != nil; {
+		entry := el.Value.(*dedupEntry)
+		prev := el.Prev()
+		if now.Sub(entry.first) >= h.window {
+			if entry.count > 1 {
+				expired = append(expired, entry)
+			}
+			h.order.Remove(el)
+			delete(h.entries, entry.key)
+		}
+		el = prev
+	}
+	h.mu.Unlock()
+
+	for _, entry := range expired {
+		summary := slog.NewRecord(now, entry.level, fmt.Sprintf("%s (suppressed repeats)", entry.msg), 0)
+		summary.AddAttrs(entry.attrs...)
+		summary.AddAttrs(
+			slog.Int("count", entry.count),
+			slog.Time("first", entry.first),
+			slog.Time("last", entry.last),
+		)
+		h.next.Handle(context.Background(), summary)
+	}
+}
+
+// recordKey hashes level+message+attrs into a stable dedup key. Attrs are
+// sorted by key first so ordering differences don't defeat deduplication.
+func recordKey(level slog.Level, msg string, attrs []slog.Attr) string {
+	sorted := make([]slog.Attr, len(attrs))
+	copy(sorted, attrs)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Key < sorted[j].Key })
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%d|%s", level, msg)
+	for _, a := range sorted {
+		fmt.Fprintf(h, "|%s=%v", a.Key, a.Value)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,72 @@
+// Package logging builds the agent-wide *slog.Logger, replacing the
+// previous package-global logrus setup so internal/sender (and friends) can
+// take a logger via their constructors instead of reaching for a global.
+package logging
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+)
+
+// New builds the agent's root logger from cfg: it selects JSON or text
+// output, renames the time/level/message keys to match the agent's existing
+// log shape, and wraps the handler in a DedupHandler so a stuck receiver
+// can't flood stdout with identical "send failed" records.
+func New(cfg config.LoggingConfig) *slog.Logger {
+	level := parseLevel(cfg.Level)
+
+	opts := &slog.HandlerOptions{
+		Level:       level,
+		ReplaceAttr: renameFields,
+	}
+
+	var handler slog.Handler
+	switch cfg.Format {
+	case "json":
+		handler = slog.NewJSONHandler(os.Stdout, opts)
+	default:
+		handler = slog.NewTextHandler(os.Stdout, opts)
+	}
+
+	logger := slog.New(NewDedupHandler(handler, 10*time.Second))
+
+	if len(cfg.Fields) > 0 {
+		attrs := make([]any, 0, len(cfg.Fields)*2)
+		for k, v := range cfg.Fields {
+			attrs = append(attrs, k, v)
+		}
+		logger = logger.With(attrs...)
+	}
+
+	return logger
+}
+
+// renameFields maps slog's default time/msg keys onto the field names the
+// agent has always emitted ("timestamp"/"message"); "level" is already
+// correct.
+func renameFields(groups []string, a slog.Attr) slog.Attr {
+	switch a.Key {
+	case slog.TimeKey:
+		a.Key = "timestamp"
+	case slog.MessageKey:
+		a.Key = "message"
+	}
+	return a
+}
+
+func parseLevel(level string) slog.Level {
+	switch strings.ToLower(level) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,198 @@
+// Package relabel applies Prometheus-style relabeling rules to a set of
+// labels. config.RelabelConfig describes each rule in the same terms as
+// Prometheus's own relabel_config: join SourceLabels with Separator,
+// match the result against Regex, and keep, drop, or rewrite the labels
+// depending on Action. Apply is a pure function so it can be used both
+// before scraping (deciding whether a discovered target is scraped at
+// all, and what labels it starts with) and after (dropping or rewriting
+// labels on each scraped series), the same way Prometheus reuses one
+// relabeling engine for both relabel_configs and metric_relabel_configs.
+//
+// Nothing calls Apply in this agent snapshot yet: the metrics-scraping
+// subsystem it would sit in front of (the internal/metrics, internal/logs,
+// internal/collector and internal/kubernetes packages main.go already
+// imports) isn't part of this checkout, so there's no scrape loop to wire
+// it into. config.MetricSourceConfig.RelabelConfigs/MetricRelabelConfigs
+// is validated and ready for that loop to call Apply against, once it
+// exists.
+package relabel
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+)
+
+// defaultSeparator matches Prometheus's own default for joining
+// source_labels before matching against regex.
+const defaultSeparator = ";"
+
+// Apply runs rules against labels in order, as Prometheus does, returning
+// the resulting label set. A "drop" or non-matching "keep" rule halts
+// processing and returns ok=false, signaling the caller (a scrape target
+// or a scraped series) should be discarded entirely.
+func Apply(labels map[string]string, rules []config.RelabelConfig) (result map[string]string, ok bool, err error) {
+	current := cloneLabels(labels)
+
+	for i, rule := range rules {
+		current, ok, err = applyOne(current, rule)
+		if err != nil {
+			return nil, false, fmt.Errorf("relabel rule %d: %w", i, err)
+		}
+		if !ok {
+			return nil, false, nil
+		}
+	}
+
+	return current, true, nil
+}
+
+func applyOne(labels map[string]string, rule config.RelabelConfig) (map[string]string, bool, error) {
+	separator := rule.Separator
+	if separator == "" {
+		separator = defaultSeparator
+	}
+
+	action := rule.Action
+	if action == "" {
+		action = "replace"
+	}
+
+	if action == "labelmap" {
+		return applyLabelmap(labels, rule)
+	}
+
+	re, err := compileRegex(rule.Regex)
+	if err != nil {
+		return nil, false, err
+	}
+
+	value := joinSourceLabels(labels, rule.SourceLabels, separator)
+
+	switch action {
+	case "keep":
+		if !re.MatchString(value) {
+			return nil, false, nil
+		}
+		return labels, true, nil
+
+	case "drop":
+		if re.MatchString(value) {
+			return nil, false, nil
+		}
+		return labels, true, nil
+
+	case "replace":
+		match := re.FindStringSubmatch(value)
+		if match == nil || rule.TargetLabel == "" {
+			return labels, true, nil
+		}
+		result := cloneLabels(labels)
+		result[rule.TargetLabel] = expandReplacement(rule.Replacement, match)
+		return result, true, nil
+
+	case "hashmod":
+		if rule.Modulus == 0 || rule.TargetLabel == "" {
+			return labels, true, nil
+		}
+		result := cloneLabels(labels)
+		result[rule.TargetLabel] = fmt.Sprintf("%d", hashMod(value, rule.Modulus))
+		return result, true, nil
+
+	default:
+		return nil, false, fmt.Errorf("unknown relabel action %q", action)
+	}
+}
+
+// applyLabelmap copies every label whose name matches rule.Regex to a new
+// label named by expanding rule.Replacement ("$1" by Prometheus's own
+// default) against the name's capture groups, e.g.
+// __meta_kubernetes_pod_label_(.+) -> $1 turns a discovery-provided
+// __meta_kubernetes_pod_label_app into a plain app label.
+func applyLabelmap(labels map[string]string, rule config.RelabelConfig) (map[string]string, bool, error) {
+	re, err := compileRegex(rule.Regex)
+	if err != nil {
+		return nil, false, err
+	}
+
+	replacement := rule.Replacement
+	if replacement == "" {
+		replacement = "$1"
+	}
+
+	result := cloneLabels(labels)
+	for name, value := range labels {
+		match := re.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+		result[expandReplacement(replacement, match)] = value
+	}
+	return result, true, nil
+}
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	if pattern == "" {
+		pattern = "(.*)"
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, fmt.Errorf("invalid regex %q: %w", pattern, err)
+	}
+	return re, nil
+}
+
+func joinSourceLabels(labels map[string]string, sourceLabels []string, separator string) string {
+	values := make([]string, len(sourceLabels))
+	for i, name := range sourceLabels {
+		values[i] = labels[name]
+	}
+	return strings.Join(values, separator)
+}
+
+// expandReplacement substitutes $1, $2, ... in replacement with match's
+// capture groups, the same as Prometheus's use of Go's regexp
+// ReplaceAll-style expansion.
+func expandReplacement(replacement string, match []string) string {
+	var dst []byte
+	for i := 0; i < len(replacement); i++ {
+		if replacement[i] == '$' && i+1 < len(replacement) && replacement[i+1] >= '0' && replacement[i+1] <= '9' {
+			group := int(replacement[i+1] - '0')
+			if group < len(match) {
+				dst = append(dst, match[group]...)
+			}
+			i++
+			continue
+		}
+		dst = append(dst, replacement[i])
+	}
+	return string(dst)
+}
+
+// hashMod hashes value with fnv-1a and reduces it mod modulus, matching
+// Prometheus's own hashmod action so the same relabel_configs section
+// shards targets identically to a Prometheus deployment doing the same
+// sharding.
+func hashMod(value string, modulus uint64) uint64 {
+	const (
+		offset64 = 14695981039346656037
+		prime64  = 1099511628211
+	)
+
+	hash := uint64(offset64)
+	for i := 0; i < len(value); i++ {
+		hash ^= uint64(value[i])
+		hash *= prime64
+	}
+	return hash % modulus
+}
+
+func cloneLabels(labels map[string]string) map[string]string {
+	result := make(map[string]string, len(labels))
+	for k, v := range labels {
+		result[k] = v
+	}
+	return result
+}
@@ -0,0 +1,246 @@
+// Package secrets resolves the license key, output TLS material, and
+// output headers from whichever source config.SecretsConfig selects for
+// each - a plain env var, a file, a Kubernetes Secret mounted via the
+// downward API, or HashiCorp Vault - and applies the resolved values on
+// top of a *config.Config, so which source backs a given secret is a
+// config choice instead of being hardcoded to an environment variable.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+)
+
+// Provider resolves a single secret value. Vault-backed Providers cache
+// the value for their configured refresh interval so repeated resolves
+// don't hit Vault every time, while still picking up a rotated lease on
+// the next refresh instead of requiring a restart.
+type Provider interface {
+	Resolve(ctx context.Context) (string, error)
+}
+
+// New builds the Provider selected by cfg.Source, defaulting to env.
+func New(cfg config.SecretSourceConfig) (Provider, error) {
+	switch cfg.Source {
+	case "", "env":
+		return envProvider{key: cfg.Env.Key}, nil
+	case "file":
+		return fileProvider{path: cfg.File.Path}, nil
+	case "k8s":
+		return fileProvider{path: filepath.Join(cfg.K8s.MountPath, cfg.K8s.Key)}, nil
+	case "vault":
+		return newVaultProvider(cfg.Vault)
+	default:
+		return nil, fmt.Errorf("unknown secret source %q", cfg.Source)
+	}
+}
+
+type envProvider struct{ key string }
+
+func (p envProvider) Resolve(ctx context.Context) (string, error) {
+	value := os.Getenv(p.key)
+	if value == "" {
+		return "", fmt.Errorf("environment variable %s is not set", p.key)
+	}
+	return value, nil
+}
+
+// fileProvider also backs the "k8s" source: a Secret mounted via the
+// downward API shows up as one file per key under its mount path, so
+// reading it is no different from any other file-based secret.
+type fileProvider struct{ path string }
+
+func (p fileProvider) Resolve(ctx context.Context) (string, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret file %s: %w", p.path, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// vaultProvider reads a key out of a HashiCorp Vault KV v2 secret,
+// caching the value for ttl so repeated Resolve calls on the same provider
+// don't re-hit Vault every time. resolve builds a new provider on every
+// call, so each ApplyOverrides call reads Vault afresh.
+type vaultProvider struct {
+	address   string
+	path      string
+	key       string
+	tokenFile string
+	ttl       time.Duration
+
+	httpClient *http.Client
+
+	mu        sync.Mutex
+	cached    string
+	expiresAt time.Time
+}
+
+func newVaultProvider(cfg config.SecretVaultConfig) (*vaultProvider, error) {
+	ttl := 5 * time.Minute
+	if cfg.RefreshInterval != "" {
+		parsed, err := time.ParseDuration(cfg.RefreshInterval)
+		if err != nil {
+			return nil, fmt.Errorf("invalid vault.refresh_interval: %w", err)
+		}
+		ttl = parsed
+	}
+
+	return &vaultProvider{
+		address:    strings.TrimSuffix(cfg.Address, "/"),
+		path:       cfg.Path,
+		key:        cfg.Key,
+		tokenFile:  cfg.TokenFile,
+		ttl:        ttl,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}, nil
+}
+
+func (p *vaultProvider) Resolve(ctx context.Context) (string, error) {
+	p.mu.Lock()
+	if p.cached != "" && time.Now().Before(p.expiresAt) {
+		value := p.cached
+		p.mu.Unlock()
+		return value, nil
+	}
+	p.mu.Unlock()
+
+	token, err := p.token()
+	if err != nil {
+		return "", fmt.Errorf("failed to read vault token: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/v1/%s", p.address, p.path)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to build vault request: %w", err)
+	}
+	req.Header.Set("X-Vault-Token", token)
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to reach vault: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault returned status %d for %s", resp.StatusCode, p.path)
+	}
+
+	var body struct {
+		Data struct {
+			Data map[string]string `json:"data"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return "", fmt.Errorf("failed to decode vault response: %w", err)
+	}
+
+	value, ok := body.Data.Data[p.key]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no key %q", p.path, p.key)
+	}
+
+	p.mu.Lock()
+	p.cached = value
+	p.expiresAt = time.Now().Add(p.ttl)
+	p.mu.Unlock()
+
+	return value, nil
+}
+
+func (p *vaultProvider) token() (string, error) {
+	if p.tokenFile == "" {
+		return "", fmt.Errorf("vault.token_file is required")
+	}
+	data, err := os.ReadFile(p.tokenFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+// ApplyOverrides resolves cfg.Secrets (where configured) and layers the
+// results on top of whatever applyEnvOverrides and the static config
+// already set: the license key backing Logs/Metrics output Authorization
+// headers, output TLS material, and any extra output headers. A license
+// or TLS secret with no Source set is left untouched, so a deployment can
+// mix sources - e.g. a Vault-sourced license key alongside file-based TLS
+// certs. A header entry with no Source is read from its env var.
+func ApplyOverrides(ctx context.Context, cfg *config.Config) error {
+	if cfg.Secrets.License.Source != "" {
+		key, err := resolve(ctx, cfg.Secrets.License)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secrets.license: %w", err)
+		}
+		setHeader(&cfg.Logs.Output, "Authorization", "Bearer "+key)
+		setHeader(&cfg.Metrics.Output, "Authorization", "Bearer "+key)
+	}
+
+	if cfg.Secrets.TLS.Source != "" {
+		dir, err := tlsMountDir(cfg.Secrets.TLS)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secrets.tls: %w", err)
+		}
+		applyTLSDir(&cfg.Logs.Output.TLS, dir)
+		applyTLSDir(&cfg.Metrics.Output.TLS, dir)
+	}
+
+	for name, src := range cfg.Secrets.Headers {
+		value, err := resolve(ctx, src)
+		if err != nil {
+			return fmt.Errorf("failed to resolve secrets.headers[%s]: %w", name, err)
+		}
+		setHeader(&cfg.Logs.Output, name, value)
+		setHeader(&cfg.Metrics.Output, name, value)
+	}
+
+	return nil
+}
+
+// tlsMountDir returns the directory a TLS Secret is mounted at (or
+// materialized to by a Vault agent sidecar). Unlike the license key and
+// headers, TLS material is several files (tls.crt/tls.key/ca.crt) rather
+// than a single value, so only the directory-based "file" and "k8s"
+// sources apply here; config.validateSecrets already rejects any other
+// secrets.tls.source.
+func tlsMountDir(cfg config.SecretSourceConfig) (string, error) {
+	switch cfg.Source {
+	case "file":
+		return cfg.File.Path, nil
+	case "k8s":
+		return cfg.K8s.MountPath, nil
+	default:
+		return "", fmt.Errorf("secrets.tls.source must be one of file|k8s, got %q", cfg.Source)
+	}
+}
+
+func applyTLSDir(tlsCfg *config.OutputTLSConfig, dir string) {
+	tlsCfg.CertFile = filepath.Join(dir, "tls.crt")
+	tlsCfg.KeyFile = filepath.Join(dir, "tls.key")
+	tlsCfg.CAFile = filepath.Join(dir, "ca.crt")
+}
+
+func setHeader(output *config.OutputConfig, name, value string) {
+	if output.Headers == nil {
+		output.Headers = make(map[string]string)
+	}
+	output.Headers[name] = value
+}
+
+func resolve(ctx context.Context, cfg config.SecretSourceConfig) (string, error) {
+	provider, err := New(cfg)
+	if err != nil {
+		return "", err
+	}
+	return provider.Resolve(ctx)
+}
@@ -0,0 +1,329 @@
+package sender
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender/wal"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	walBytesWritten = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "killkrill_agent_wal_bytes_written_total",
+			Help: "Total bytes written to the on-disk write-ahead buffer",
+		},
+		[]string{"type"},
+	)
+
+	walBytesRead = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "killkrill_agent_wal_bytes_read_total",
+			Help: "Total bytes read back from the on-disk write-ahead buffer",
+		},
+		[]string{"type"},
+	)
+
+	walDropped = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "killkrill_agent_wal_dropped_messages_total",
+			Help: "Total messages dropped by the send buffer",
+		},
+		[]string{"type", "reason"},
+	)
+
+	walDepthBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "killkrill_agent_wal_depth_bytes",
+			Help: "Current size of the on-disk write-ahead buffer",
+		},
+		[]string{"type"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(walBytesWritten)
+	prometheus.MustRegister(walBytesRead)
+	prometheus.MustRegister(walDropped)
+	prometheus.MustRegister(walDepthBytes)
+}
+
+// messageBuffer decouples Send() from the network: the memory
+// implementation is the historical bounded channel, the disk implementation
+// spills to a wal.WAL so a degraded network doesn't silently lose telemetry.
+type messageBuffer interface {
+	push(msg *Message) bool
+	messages() <-chan *Message
+	close()
+	// stats reports the buffer kind plus its current/max depth for health
+	// reporting; max is 0 when the buffer has no fixed capacity.
+	stats() (kind string, depth, max int)
+}
+
+// newMessageBuffer builds the buffer configured for an output.
+func newMessageBuffer(cfg config.OutputConfig, dataType string, logger *slog.Logger) (messageBuffer, error) {
+	capacity := cfg.BatchSize * 2
+	if capacity <= 0 {
+		capacity = 2000
+	}
+
+	switch cfg.Buffer.Type {
+	case "disk":
+		return newDiskBuffer(cfg, dataType, logger)
+	default:
+		return newMemoryBuffer(capacity, cfg.Buffer.OnFull, dataType), nil
+	}
+}
+
+type memoryBuffer struct {
+	ch       chan *Message
+	onFull   string
+	dataType string
+}
+
+func newMemoryBuffer(capacity int, onFull, dataType string) *memoryBuffer {
+	if onFull == "" {
+		onFull = "drop_newest"
+	}
+	return &memoryBuffer{ch: make(chan *Message, capacity), onFull: onFull, dataType: dataType}
+}
+
+func (b *memoryBuffer) push(msg *Message) bool {
+	select {
+	case b.ch <- msg:
+		return true
+	default:
+	}
+
+	switch b.onFull {
+	case "block":
+		b.ch <- msg
+		return true
+	case "drop_oldest":
+		select {
+		case <-b.ch:
+			walDropped.WithLabelValues(b.dataType, "drop_oldest").Inc()
+		default:
+		}
+		select {
+		case b.ch <- msg:
+			return true
+		default:
+			walDropped.WithLabelValues(b.dataType, "drop_newest").Inc()
+			return false
+		}
+	default: // drop_newest
+		walDropped.WithLabelValues(b.dataType, "drop_newest").Inc()
+		return false
+	}
+}
+
+func (b *memoryBuffer) messages() <-chan *Message { return b.ch }
+func (b *memoryBuffer) close()                    {}
+func (b *memoryBuffer) stats() (string, int, int) {
+	return "memory", len(b.ch), cap(b.ch)
+}
+
+// diskBuffer spills to a segmented WAL on disk, so messages survive a
+// receiver outage instead of being dropped once the in-process channel
+// fills up.
+type diskBuffer struct {
+	w        *wal.WAL
+	reader   *wal.Reader
+	out      chan *Message
+	stopCh   chan struct{}
+	dataType string
+	onFull   string
+	maxBytes int64
+	logger   *slog.Logger
+
+	// ackMu guards pending, the FIFO of records replay has handed off but
+	// that haven't been acked yet. Senders call a record's Ack once it's
+	// actually delivered, which may happen out of order across batches;
+	// pending lets replay keep several records in flight while still only
+	// ever persisting a cumulative "acked up to here" position, so a
+	// crash never loses a record whose Ack hasn't fired even if later
+	// records already have.
+	ackMu   sync.Mutex
+	pending []*pendingAck
+}
+
+// pendingAck is one replayed-but-not-yet-acked WAL record's position.
+type pendingAck struct {
+	segmentID int64
+	offset    int64
+	acked     bool
+}
+
+func newDiskBuffer(cfg config.OutputConfig, dataType string, logger *slog.Logger) (*diskBuffer, error) {
+	w, err := wal.Open(cfg.Buffer.BaseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, err := wal.NewReader(w)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := cfg.BatchSize * 2
+	if capacity <= 0 {
+		capacity = 2000
+	}
+
+	maxBytes := cfg.Buffer.MaxBytes.Bytes()
+	onFull := cfg.Buffer.OnFull
+	if onFull == "" {
+		onFull = "drop_newest"
+	}
+
+	b := &diskBuffer{
+		w:        w,
+		reader:   reader,
+		out:      make(chan *Message, capacity),
+		stopCh:   make(chan struct{}),
+		dataType: dataType,
+		onFull:   onFull,
+		maxBytes: maxBytes,
+		logger:   logger,
+	}
+
+	go b.replay()
+
+	return b, nil
+}
+
+func (b *diskBuffer) push(msg *Message) bool {
+	if b.maxBytes > 0 {
+		if depth, err := b.w.Depth(); err == nil && depth >= b.maxBytes {
+			switch b.onFull {
+			case "block":
+				// Fall through to append; the operator accepted unbounded
+				// growth in exchange for never dropping data.
+			default:
+				walDropped.WithLabelValues(b.dataType, b.onFull).Inc()
+				return false
+			}
+		}
+	}
+
+	data, err := json.Marshal(msg)
+	if err != nil {
+		b.logger.Error("Failed to marshal message for WAL", "error", err)
+		return false
+	}
+
+	if err := b.w.Append(data); err != nil {
+		b.logger.Error("Failed to append to WAL", "error", err)
+		return false
+	}
+
+	walBytesWritten.WithLabelValues(b.dataType).Add(float64(len(data)))
+	if depth, err := b.w.Depth(); err == nil {
+		walDepthBytes.WithLabelValues(b.dataType).Set(float64(depth))
+	}
+	return true
+}
+
+// replay feeds decoded WAL records into the out channel, recording each
+// one's position in b.pending before handing it off. A record's Ack - set
+// by the sender that actually delivers it - marks its pendingAck acked and
+// advances the persisted WAL position up to the longest unbroken prefix of
+// acked records at the front of the queue, so the position always means
+// "confirmed sent", not merely "dequeued", even though several records can
+// be in flight (across different batches, acked out of order) at once. A
+// crash before a message's Ack fires leaves the WAL position at or before
+// it, so it's replayed again on restart instead of lost.
+func (b *diskBuffer) replay() {
+	for {
+		select {
+		case <-b.stopCh:
+			return
+		default:
+		}
+
+		record, err := b.reader.Next()
+		if err != nil {
+			time.Sleep(200 * time.Millisecond)
+			continue
+		}
+
+		segmentID, offset := b.reader.Position()
+		entry := &pendingAck{segmentID: segmentID, offset: offset}
+		b.ackMu.Lock()
+		b.pending = append(b.pending, entry)
+		b.ackMu.Unlock()
+
+		var msg Message
+		if err := json.Unmarshal(record, &msg); err != nil {
+			b.logger.Error("Failed to decode WAL record, skipping", "error", err)
+			b.ackRecord(entry, len(record))
+			continue
+		}
+
+		recordLen := len(record)
+		msg.Ack = func() {
+			b.ackRecord(entry, recordLen)
+		}
+
+		select {
+		case b.out <- &msg:
+		case <-b.stopCh:
+			return
+		}
+	}
+}
+
+// ackRecord marks entry delivered and, if it's at (or has become, once
+// earlier entries are also acked) the front of b.pending, persists the WAL
+// position up through the longest run of now-acked entries at the front of
+// the queue.
+func (b *diskBuffer) ackRecord(entry *pendingAck, recordLen int) {
+	b.ackMu.Lock()
+	entry.acked = true
+
+	var advanceTo *pendingAck
+	i := 0
+	for ; i < len(b.pending) && b.pending[i].acked; i++ {
+		advanceTo = b.pending[i]
+	}
+	b.pending = b.pending[i:]
+	b.ackMu.Unlock()
+
+	walBytesRead.WithLabelValues(b.dataType).Add(float64(recordLen))
+
+	if advanceTo != nil {
+		b.reader.AckPosition(advanceTo.segmentID, advanceTo.offset)
+		if depth, err := b.w.Depth(); err == nil {
+			walDepthBytes.WithLabelValues(b.dataType).Set(float64(depth))
+		}
+	}
+}
+
+func (b *diskBuffer) messages() <-chan *Message { return b.out }
+
+func (b *diskBuffer) stats() (string, int, int) {
+	depth, _ := b.w.Depth()
+	return "disk", int(depth), int(b.maxBytes)
+}
+
+// ackBatch calls every message's Ack, if set, once a sender has confirmed
+// the whole batch was delivered. Messages sourced from a memoryBuffer have
+// no Ack to call.
+func ackBatch(batch []*Message) {
+	for _, msg := range batch {
+		if msg.Ack != nil {
+			msg.Ack()
+		}
+	}
+}
+
+func (b *diskBuffer) close() {
+	close(b.stopCh)
+	b.reader.Close()
+	b.w.Close()
+}
@@ -4,19 +4,22 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/tls"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender/negotiator"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender/tlsauth"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/sirupsen/logrus"
 	"github.com/quic-go/quic-go"
 	"github.com/quic-go/quic-go/http3"
 )
@@ -85,34 +88,44 @@ func init() {
 
 // Message represents a message to be sent
 type Message struct {
-	Data      interface{} `json:"data"`
-	Timestamp time.Time   `json:"timestamp"`
-	Type      string      `json:"type"`
+	Data      interface{}            `json:"data"`
+	Timestamp time.Time              `json:"timestamp"`
+	Type      string                 `json:"type"`
 	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+
+	// Ack, when set, is called once this message has actually been
+	// delivered - a diskBuffer sets it to acknowledge the WAL record it
+	// was replayed from, so a crash before delivery redelivers the
+	// message instead of losing it. nil for messages that arrived
+	// through a memoryBuffer, which has nothing to acknowledge.
+	Ack func() `json:"-"`
 }
 
 // HTTP3Sender sends messages using HTTP3/QUIC protocol with HTTP1.1 fallback
 type HTTP3Sender struct {
-	config       config.OutputConfig
-	http3Client  *http.Client
-	http1Client  *http.Client
-	dataType     string
-	batchCh      chan *Message
-	stopCh       chan struct{}
-	wg           sync.WaitGroup
-	logger       *logrus.Entry
-	useHTTP3     bool
+	config        config.OutputConfig
+	http3Client   *http.Client
+	http1Client   *http.Client
+	dataType      string
+	buffer        messageBuffer
+	stopCh        chan struct{}
+	wg            sync.WaitGroup
+	logger        *slog.Logger
+	useHTTP3      bool
 	fallbackMutex sync.RWMutex
-	fallbackURL  string
-	lastFallback time.Time
+	fallbackURL   string
+	lastFallback  time.Time
+	negotiator    *negotiator.Negotiator
+	host          string
+	tlsAuth       *tlsauth.Manager
+	closed        atomic.Bool
 }
 
 // NewHTTP3Sender creates a new HTTP3 sender with HTTP1.1 fallback
-func NewHTTP3Sender(cfg config.OutputConfig, dataType string) (*HTTP3Sender, error) {
-	// Create shared TLS config
-	tlsConfig := &tls.Config{
-		InsecureSkipVerify: true, // Allow self-signed certs in dev
-		NextProtos:         []string{"h3", "h2", "http/1.1"},
+func NewHTTP3Sender(cfg config.OutputConfig, dataType string, logger *slog.Logger) (*HTTP3Sender, error) {
+	tlsAuth, err := tlsauth.New(cfg.TLS, cfg.Auth, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS/auth: %w", err)
 	}
 
 	// Create HTTP3/QUIC client
@@ -122,7 +135,7 @@ func NewHTTP3Sender(cfg config.OutputConfig, dataType string) (*HTTP3Sender, err
 	}
 
 	http3RoundTripper := &http3.RoundTripper{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig: tlsAuth.TLSConfig(),
 		QuicConfig:      quicConfig,
 	}
 
@@ -133,7 +146,7 @@ func NewHTTP3Sender(cfg config.OutputConfig, dataType string) (*HTTP3Sender, err
 
 	// Create HTTP1.1 fallback client
 	http1Transport := &http.Transport{
-		TLSClientConfig: tlsConfig,
+		TLSClientConfig: tlsAuth.TLSConfig(),
 		DialContext: (&net.Dialer{
 			Timeout:   10 * time.Second,
 			KeepAlive: 30 * time.Second,
@@ -144,6 +157,10 @@ func NewHTTP3Sender(cfg config.OutputConfig, dataType string) (*HTTP3Sender, err
 		DisableCompression:  false,
 	}
 
+	// Attach hands both transports to the manager so a cert/token rotation
+	// detected later swaps the new TLS config into both at once.
+	tlsAuth.Attach(http3RoundTripper, http1Transport)
+
 	http1Client := &http.Client{
 		Transport: http1Transport,
 		Timeout:   30 * time.Second,
@@ -171,25 +188,35 @@ func NewHTTP3Sender(cfg config.OutputConfig, dataType string) (*HTTP3Sender, err
 	// Keep HTTPS for py4web (it can handle TLS too)
 	// But allow HTTP if explicitly configured for development
 
+	logger = logger.With("component", fmt.Sprintf("adaptive_sender_%s", dataType))
+
+	buffer, err := newMessageBuffer(cfg, dataType, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create send buffer: %w", err)
+	}
+
 	sender := &HTTP3Sender{
 		config:      cfg,
 		http3Client: http3Client,
 		http1Client: http1Client,
 		dataType:    dataType,
-		batchCh:     make(chan *Message, cfg.BatchSize*2),
+		buffer:      buffer,
 		stopCh:      make(chan struct{}),
-		logger:      logrus.WithField("component", fmt.Sprintf("adaptive_sender_%s", dataType)),
+		logger:      logger,
 		useHTTP3:    true, // Start with HTTP3
 		fallbackURL: fallbackURL,
+		negotiator:  negotiator.New(1, 250*time.Millisecond, 5*time.Minute),
+		host:        hostOf(cfg.URL),
+		tlsAuth:     tlsAuth,
 	}
 
 	// Set initial protocol metric
 	currentProtocol.WithLabelValues(dataType).Set(3)
 
-	sender.logger.WithFields(logrus.Fields{
-		"http3_url":    cfg.URL,
-		"fallback_url": fallbackURL,
-	}).Info("Adaptive sender initialized with HTTP3 primary and HTTP1.1 fallback")
+	sender.logger.Info("Adaptive sender initialized with HTTP3 primary and HTTP1.1 fallback",
+		"http3_url", cfg.URL,
+		"fallback_url", fallbackURL,
+	)
 
 	// Start batch processor
 	sender.wg.Add(1)
@@ -200,6 +227,10 @@ func NewHTTP3Sender(cfg config.OutputConfig, dataType string) (*HTTP3Sender, err
 
 // Send queues a message for sending
 func (s *HTTP3Sender) Send(data interface{}, metadata map[string]interface{}) error {
+	if s.closed.Load() {
+		return fmt.Errorf("http3 sender: closed, not accepting new messages")
+	}
+
 	msg := &Message{
 		Data:      data,
 		Timestamp: time.Now(),
@@ -207,25 +238,75 @@ func (s *HTTP3Sender) Send(data interface{}, metadata map[string]interface{}) er
 		Metadata:  metadata,
 	}
 
-	select {
-	case s.batchCh <- msg:
-		return nil
-	default:
+	if !s.buffer.push(msg) {
 		s.logger.Warn("Send buffer full, dropping message")
 		sentMessages.WithLabelValues(s.dataType, "dropped").Inc()
 		return fmt.Errorf("send buffer full")
 	}
+	return nil
 }
 
-// Close gracefully shuts down the sender
-func (s *HTTP3Sender) Close() {
+// Shutdown performs a two-phase shutdown: phase 1 stops accepting new
+// Send() calls, lets batchProcessor flush whatever batch it already had in
+// hand, then drains anything still sitting in the buffer against ctx's
+// deadline; phase 2 closes the HTTP3/HTTP1.1 transports, which is only safe
+// once phase 1 has confirmed no send is still in flight.
+func (s *HTTP3Sender) Shutdown(ctx context.Context) error {
+	s.closed.Store(true)
+
 	close(s.stopCh)
 	s.wg.Wait()
 
-	// Close HTTP3 transport
+	s.drainRemaining(ctx)
+
+	s.buffer.close()
+
 	if roundTripper, ok := s.http3Client.Transport.(*http3.RoundTripper); ok {
 		roundTripper.Close()
 	}
+	if transport, ok := s.http1Client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
+
+	s.tlsAuth.Close()
+	return nil
+}
+
+// drainRemaining flushes any messages still sitting in the buffer that
+// batchProcessor's final select hadn't picked up before it returned, giving
+// up once ctx expires or the buffer has been idle for drainIdleTimeout.
+func (s *HTTP3Sender) drainRemaining(ctx context.Context) {
+	batch := make([]*Message, 0, s.config.BatchSize)
+	idle := time.NewTimer(drainIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case msg := <-s.buffer.messages():
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(drainIdleTimeout)
+
+			batch = append(batch, msg)
+			if len(batch) >= s.config.BatchSize {
+				s.sendBatch(ctx, batch)
+				batch = batch[:0]
+			}
+
+		case <-idle.C:
+			if len(batch) > 0 {
+				s.sendBatch(ctx, batch)
+			}
+			return
+
+		case <-ctx.Done():
+			if len(batch) > 0 {
+				s.sendBatch(ctx, batch)
+			}
+			return
+		}
+	}
 }
 
 // batchProcessor processes batches of messages
@@ -234,7 +315,7 @@ func (s *HTTP3Sender) batchProcessor() {
 
 	flushInterval, err := time.ParseDuration(s.config.FlushInterval)
 	if err != nil {
-		s.logger.Errorf("Invalid flush interval %s, using 5s", s.config.FlushInterval)
+		s.logger.Error("Invalid flush interval, using default", "flush_interval", s.config.FlushInterval, "default", "5s")
 		flushInterval = 5 * time.Second
 	}
 
@@ -245,23 +326,23 @@ func (s *HTTP3Sender) batchProcessor() {
 
 	for {
 		select {
-		case msg := <-s.batchCh:
+		case msg := <-s.buffer.messages():
 			batch = append(batch, msg)
 			if len(batch) >= s.config.BatchSize {
-				s.sendBatch(batch)
+				s.sendBatch(context.Background(), batch)
 				batch = batch[:0] // Reset slice but keep capacity
 			}
 
 		case <-ticker.C:
 			if len(batch) > 0 {
-				s.sendBatch(batch)
+				s.sendBatch(context.Background(), batch)
 				batch = batch[:0]
 			}
 
 		case <-s.stopCh:
 			// Send remaining messages
 			if len(batch) > 0 {
-				s.sendBatch(batch)
+				s.sendBatch(context.Background(), batch)
 			}
 			return
 		}
@@ -269,7 +350,7 @@ func (s *HTTP3Sender) batchProcessor() {
 }
 
 // sendBatch sends a batch of messages
-func (s *HTTP3Sender) sendBatch(batch []*Message) {
+func (s *HTTP3Sender) sendBatch(ctx context.Context, batch []*Message) {
 	if len(batch) == 0 {
 		return
 	}
@@ -289,7 +370,7 @@ func (s *HTTP3Sender) sendBatch(batch []*Message) {
 	// Serialize to JSON
 	jsonData, err := json.Marshal(payload)
 	if err != nil {
-		s.logger.Errorf("Failed to marshal batch: %v", err)
+		s.logger.Error("Failed to marshal batch", "error", err)
 		sentMessages.WithLabelValues(s.dataType, "marshal_error").Add(float64(len(batch)))
 		return
 	}
@@ -302,7 +383,7 @@ func (s *HTTP3Sender) sendBatch(batch []*Message) {
 		var buf bytes.Buffer
 		gzipWriter := gzip.NewWriter(&buf)
 		if _, err := gzipWriter.Write(jsonData); err != nil {
-			s.logger.Errorf("Failed to compress batch: %v", err)
+			s.logger.Error("Failed to compress batch", "error", err)
 			sentMessages.WithLabelValues(s.dataType, "compression_error").Add(float64(len(batch)))
 			return
 		}
@@ -312,11 +393,12 @@ func (s *HTTP3Sender) sendBatch(batch []*Message) {
 	}
 
 	// Send with retries and protocol fallback
-	if err := s.sendWithFallback(body, contentEncoding, len(batch)); err != nil {
-		s.logger.Errorf("Failed to send batch after retries and fallback: %v", err)
+	if err := s.sendWithFallback(ctx, body, contentEncoding, len(batch)); err != nil {
+		s.logger.Error("Failed to send batch after retries and fallback", "error", err)
 		sentMessages.WithLabelValues(s.dataType, "failed").Add(float64(len(batch)))
 		protocol = "failed"
 	} else {
+		ackBatch(batch)
 		sentMessages.WithLabelValues(s.dataType, "success").Add(float64(len(batch)))
 		sentBytes.WithLabelValues(s.dataType, "success").Add(float64(len(jsonData)))
 
@@ -333,63 +415,140 @@ func (s *HTTP3Sender) sendBatch(batch []*Message) {
 	sendDuration.WithLabelValues(s.dataType, protocol).Observe(time.Since(start).Seconds())
 }
 
-// sendWithFallback sends data with retry logic and HTTP1.1 fallback
-func (s *HTTP3Sender) sendWithFallback(body io.Reader, contentEncoding string, messageCount int) error {
-	backoffDuration, err := time.ParseDuration(s.config.RetryBackoff)
+// sendWithFallback sends data over whichever protocol the negotiator has
+// decided on for s.host. On first contact (no cached winner yet) it hands
+// the send itself to negotiator.Race so the race's outcome - not a
+// hardcoded HTTP3-first guess - picks the protocol; once a winner is
+// cached, it goes straight to that protocol with the usual fallback.
+func (s *HTTP3Sender) sendWithFallback(ctx context.Context, body io.Reader, contentEncoding string, messageCount int) error {
+	bodyBytes, err := io.ReadAll(body)
 	if err != nil {
-		backoffDuration = time.Second
+		return fmt.Errorf("failed to buffer batch for send: %w", err)
+	}
+
+	if _, cached := s.negotiator.Lookup(s.host); !cached {
+		return s.raceProtocols(ctx, bodyBytes, contentEncoding, messageCount)
+	}
+
+	return s.sendWithCachedProtocol(ctx, bodyBytes, contentEncoding, messageCount)
+}
+
+// raceProtocols runs on first contact with s.host: it hands both protocols'
+// full send attempts to negotiator.Race, which starts HTTP3 immediately and
+// HTTP1.1 after a short head start, and keeps whichever completes a
+// successful round trip first. This replaces paying a full sequential
+// try-HTTP3-then-fallback round trip on every new host.
+func (s *HTTP3Sender) raceProtocols(ctx context.Context, bodyBytes []byte, contentEncoding string, messageCount int) error {
+	dialH3 := func() error {
+		return s.sendWithProtocol(ctx, s.http3Client, s.config.URL, bytes.NewReader(bodyBytes), contentEncoding, messageCount, "HTTP3")
+	}
+	dialH2 := func() error {
+		return s.sendWithProtocol(ctx, s.http1Client, s.fallbackURL, bytes.NewReader(bodyBytes), contentEncoding, messageCount, "HTTP1.1")
+	}
+
+	protocol, err := s.negotiator.Race(s.host, dialH3, dialH2)
+	if err != nil {
+		return fmt.Errorf("both HTTP3 and HTTP1.1 failed: %w", err)
 	}
 
-	// Try HTTP3 first if enabled
+	s.fallbackMutex.Lock()
+	s.useHTTP3 = protocol == negotiator.ProtocolH3
+	if !s.useHTTP3 {
+		s.lastFallback = time.Now()
+	}
+	s.fallbackMutex.Unlock()
+
+	if protocol == negotiator.ProtocolH3 {
+		currentProtocol.WithLabelValues(s.dataType).Set(3)
+	} else {
+		currentProtocol.WithLabelValues(s.dataType).Set(1)
+		protocolFallbacks.WithLabelValues(s.dataType, "race_lost").Inc()
+	}
+
+	return nil
+}
+
+// sendWithCachedProtocol sends over whichever protocol the negotiator has
+// already decided on for s.host, falling back to HTTP1.1 on an HTTP3
+// failure and restoring HTTP3 once the negotiator's cached HTTP1.1 result
+// has expired (or an Alt-Svc response already upgraded it), instead of a
+// hard-coded cooldown window.
+func (s *HTTP3Sender) sendWithCachedProtocol(ctx context.Context, bodyBytes []byte, contentEncoding string, messageCount int) error {
 	s.fallbackMutex.RLock()
 	useHTTP3 := s.useHTTP3
 	s.fallbackMutex.RUnlock()
 
 	if useHTTP3 {
-		if err := s.sendWithProtocol(s.http3Client, s.config.URL, body, contentEncoding, messageCount, "HTTP3"); err != nil {
-			s.logger.WithError(err).Warn("HTTP3 send failed, falling back to HTTP1.1")
+		if err := s.sendWithProtocol(ctx, s.http3Client, s.config.URL, bytes.NewReader(bodyBytes), contentEncoding, messageCount, "HTTP3"); err != nil {
+			reason := negotiatorReason(negotiator.ClassifyError(err))
+			s.logger.Warn("HTTP3 send failed, falling back to HTTP1.1", "error", err, "reason", reason)
 
-			// Mark fallback and update metrics
+			// Mark fallback and update metrics. The negotiator remembers
+			// HTTP1.1 as the winner for this host until its TTL expires or
+			// an Alt-Svc response tells us h3 is reachable again.
 			s.fallbackMutex.Lock()
 			s.useHTTP3 = false
 			s.lastFallback = time.Now()
 			s.fallbackMutex.Unlock()
+			s.negotiator.Remember(s.host, negotiator.ProtocolH1)
 
 			currentProtocol.WithLabelValues(s.dataType).Set(1)
-			protocolFallbacks.WithLabelValues(s.dataType, "http3_failed").Inc()
-
-			// Reset body reader for retry
-			if seeker, ok := body.(io.Seeker); ok {
-				seeker.Seek(0, io.SeekStart)
-			}
+			protocolFallbacks.WithLabelValues(s.dataType, reason).Inc()
 		} else {
 			return nil // HTTP3 success
 		}
 	}
 
 	// Try HTTP1.1 fallback
-	if err := s.sendWithProtocol(s.http1Client, s.fallbackURL, body, contentEncoding, messageCount, "HTTP1.1"); err != nil {
+	if err := s.sendWithProtocol(ctx, s.http1Client, s.fallbackURL, bytes.NewReader(bodyBytes), contentEncoding, messageCount, "HTTP1.1"); err != nil {
 		return fmt.Errorf("both HTTP3 and HTTP1.1 failed: %w", err)
 	}
 
-	// Check if we should retry HTTP3 after some time
-	s.fallbackMutex.RLock()
-	lastFallback := s.lastFallback
-	s.fallbackMutex.RUnlock()
-
-	if time.Since(lastFallback) > 5*time.Minute {
-		s.logger.Info("Attempting to restore HTTP3 connection")
+	// Restore HTTP3 once the negotiator's cached HTTP1.1 winner has expired
+	// (or an Alt-Svc response already upgraded it), instead of a hard-coded
+	// cooldown window.
+	if protocol, cached := s.negotiator.Lookup(s.host); !cached || protocol == negotiator.ProtocolH3 {
 		s.fallbackMutex.Lock()
+		wasHTTP3 := s.useHTTP3
 		s.useHTTP3 = true
 		s.fallbackMutex.Unlock()
-		currentProtocol.WithLabelValues(s.dataType).Set(3)
+
+		if !wasHTTP3 {
+			s.logger.Info("Attempting to restore HTTP3 connection")
+			currentProtocol.WithLabelValues(s.dataType).Set(3)
+		}
 	}
 
 	return nil
 }
 
+// negotiatorReason maps a negotiator.ErrorClass to a short Prometheus label
+// value for the protocol_fallbacks_total counter.
+func negotiatorReason(class negotiator.ErrorClass) string {
+	switch class {
+	case negotiator.ErrorQUICVersionMismatch:
+		return "quic_version_mismatch"
+	case negotiator.ErrorQUICIdleTimeout:
+		return "quic_idle_timeout"
+	case negotiator.ErrorICMPUnreachable:
+		return "icmp_unreachable"
+	default:
+		return "http3_failed"
+	}
+}
+
+// hostOf extracts the host:port used as the negotiator cache key from an
+// output URL.
+func hostOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return u.Host
+}
+
 // sendWithProtocol sends data with a specific protocol client
-func (s *HTTP3Sender) sendWithProtocol(client *http.Client, url string, body io.Reader, contentEncoding string, messageCount int, protocolName string) error {
+func (s *HTTP3Sender) sendWithProtocol(ctx context.Context, client *http.Client, url string, body io.Reader, contentEncoding string, messageCount int, protocolName string) error {
 	var lastErr error
 
 	backoffDuration, err := time.ParseDuration(s.config.RetryBackoff)
@@ -398,8 +557,12 @@ func (s *HTTP3Sender) sendWithProtocol(client *http.Client, url string, body io.
 	}
 
 	for attempt := 0; attempt <= s.config.RetryAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s send aborted: %w", protocolName, ctx.Err())
+		}
+
 		if attempt > 0 {
-			s.logger.Debugf("%s retry attempt %d/%d", protocolName, attempt, s.config.RetryAttempts)
+			s.logger.Debug("retry attempt", "protocol", protocolName, "attempt", attempt, "max_attempts", s.config.RetryAttempts)
 			time.Sleep(backoffDuration * time.Duration(attempt))
 
 			// Reset body reader for retry
@@ -409,7 +572,7 @@ func (s *HTTP3Sender) sendWithProtocol(client *http.Client, url string, body io.
 		}
 
 		// Create request
-		req, err := http.NewRequest("POST", url, body)
+		req, err := http.NewRequestWithContext(ctx, "POST", url, body)
 		if err != nil {
 			lastErr = fmt.Errorf("failed to create %s request: %w", protocolName, err)
 			continue
@@ -430,13 +593,20 @@ func (s *HTTP3Sender) sendWithProtocol(client *http.Client, url string, body io.
 			req.Header.Set(key, value)
 		}
 
+		// A configured Auth mode overrides any static Authorization header.
+		if authHeader := s.tlsAuth.AuthHeader(); authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+
 		// Send request
 		resp, err := client.Do(req)
 		if err != nil {
 			lastErr = fmt.Errorf("%s request failed: %w", protocolName, err)
 
-			// For HTTP3, certain errors indicate protocol unavailability
-			if protocolName == "HTTP3" && isHTTP3UnavailableError(err) {
+			// For HTTP3, certain typed errors indicate the protocol itself
+			// is unavailable on this path (blocked UDP, MTU blackhole, etc)
+			// rather than a transient failure worth retrying.
+			if protocolName == "HTTP3" && negotiator.ClassifyError(err) != negotiator.ErrorOther {
 				return fmt.Errorf("HTTP3 unavailable: %w", err)
 			}
 			continue
@@ -445,7 +615,16 @@ func (s *HTTP3Sender) sendWithProtocol(client *http.Client, url string, body io.
 		// Check response
 		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
 			resp.Body.Close()
-			s.logger.Debugf("%s send successful to %s", protocolName, url)
+			s.logger.Debug("send successful", "protocol", protocolName, "url", url)
+
+			// A non-H3 response may advertise h3 support via Alt-Svc; if so,
+			// the negotiator upgrades the cached winner without waiting for
+			// the TTL to expire.
+			if protocolName != "HTTP3" {
+				if altSvc := resp.Header.Get("Alt-Svc"); altSvc != "" {
+					s.negotiator.ObserveAltSvc(s.host, altSvc)
+				}
+			}
 			return nil
 		}
 
@@ -464,34 +643,6 @@ func (s *HTTP3Sender) sendWithProtocol(client *http.Client, url string, body io.
 	return lastErr
 }
 
-// isHTTP3UnavailableError checks if the error indicates HTTP3/QUIC is unavailable
-func isHTTP3UnavailableError(err error) bool {
-	if err == nil {
-		return false
-	}
-
-	errStr := err.Error()
-
-	// Common HTTP3/QUIC unavailability indicators
-	indicators := []string{
-		"no such host",
-		"connection refused",
-		"protocol not supported",
-		"quic",
-		"udp",
-		"timeout",
-		"network unreachable",
-	}
-
-	for _, indicator := range indicators {
-		if strings.Contains(strings.ToLower(errStr), indicator) {
-			return true
-		}
-	}
-
-	return false
-}
-
 // Health returns the health status of the sender
 func (s *HTTP3Sender) Health() map[string]interface{} {
 	s.fallbackMutex.RLock()
@@ -502,20 +653,23 @@ func (s *HTTP3Sender) Health() map[string]interface{} {
 	lastFallback := s.lastFallback
 	s.fallbackMutex.RUnlock()
 
+	bufferKind, bufferDepth, bufferMax := s.buffer.stats()
+
 	return map[string]interface{}{
-		"type":         "adaptive_sender",
-		"data_type":    s.dataType,
-		"http3_url":    s.config.URL,
-		"fallback_url": s.fallbackURL,
+		"type":             "adaptive_sender",
+		"data_type":        s.dataType,
+		"http3_url":        s.config.URL,
+		"fallback_url":     s.fallbackURL,
 		"current_protocol": currentProtocol,
 		"last_fallback":    lastFallback,
-		"buffer_size":  len(s.batchCh),
-		"buffer_cap":   cap(s.batchCh),
-		"compression":  s.config.Compression,
-		"batch_size":   s.config.BatchSize,
+		"buffer_type":      bufferKind,
+		"buffer_size":      bufferDepth,
+		"buffer_cap":       bufferMax,
+		"compression":      s.config.Compression,
+		"batch_size":       s.config.BatchSize,
 		"retry_config": map[string]interface{}{
 			"attempts": s.config.RetryAttempts,
 			"backoff":  s.config.RetryBackoff,
 		},
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,89 @@
+package sender
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender/negotiator"
+)
+
+// TestHTTP3Sender_ShutdownDrainsWithoutLoss simulates a slow receiver and
+// verifies that Shutdown flushes every message queued before it stopped
+// accepting new Send() calls, instead of only the partial batch
+// batchProcessor happened to be holding when it returned.
+func TestHTTP3Sender_ShutdownDrainsWithoutLoss(t *testing.T) {
+	var mu sync.Mutex
+	received := 0
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(50 * time.Millisecond) // slow receiver
+
+		var payload struct {
+			Count int `json:"count"`
+		}
+		body, _ := io.ReadAll(r.Body)
+		json.Unmarshal(body, &payload)
+
+		mu.Lock()
+		received += payload.Count
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	cfg := config.OutputConfig{
+		URL:           server.URL,
+		BatchSize:     5,
+		FlushInterval: "1h", // effectively disabled for this test
+		RetryAttempts: 0,
+		RetryBackoff:  "10ms",
+		Buffer: config.BufferConfig{
+			Type:   "memory",
+			OnFull: "block",
+		},
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	s, err := NewHTTP3Sender(cfg, "logs", logger)
+	if err != nil {
+		t.Fatalf("NewHTTP3Sender() error = %v", err)
+	}
+
+	// Skip the QUIC attempt and go straight to the HTTP1.1 fallback path,
+	// which (for a plain httptest.Server URL) targets the same server.
+	s.useHTTP3 = false
+	s.negotiator.Remember(s.host, negotiator.ProtocolH1)
+
+	const total = 23
+	for i := 0; i < total; i++ {
+		if err := s.Send(map[string]int{"i": i}, nil); err != nil {
+			t.Fatalf("Send(%d) error = %v", i, err)
+		}
+	}
+
+	drainCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := s.Shutdown(drainCtx); err != nil {
+		t.Fatalf("Shutdown() error = %v", err)
+	}
+
+	if err := s.Send(map[string]int{}, nil); err == nil {
+		t.Fatalf("expected Send() after Shutdown() to be rejected")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if received != total {
+		t.Fatalf("receiver got %d messages, want %d (messages lost during shutdown)", received, total)
+	}
+}
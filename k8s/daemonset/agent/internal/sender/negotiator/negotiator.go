@@ -0,0 +1,224 @@
+// Package negotiator picks a transport protocol (HTTP/3, HTTP/2, or
+// HTTP/1.1) per destination host, races HTTP/3 against HTTP/2 on first
+// contact, and remembers the winner so later sends skip straight to the
+// protocol that's known to work on that network path.
+package negotiator
+
+import (
+	"container/list"
+	"errors"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+// Protocol identifies the transport protocol a negotiation settled on.
+type Protocol string
+
+const (
+	ProtocolH3 Protocol = "h3"
+	ProtocolH2 Protocol = "h2"
+	ProtocolH1 Protocol = "http/1.1"
+)
+
+// ErrorClass categorizes a dial/send failure so callers can react without
+// resorting to substring matching on error text.
+type ErrorClass int
+
+const (
+	// ErrorOther is any failure not recognized as QUIC/UDP-specific.
+	ErrorOther ErrorClass = iota
+	// ErrorQUICVersionMismatch means the peer doesn't speak a QUIC version
+	// we support (quic.VersionNegotiationError).
+	ErrorQUICVersionMismatch
+	// ErrorQUICIdleTimeout means the QUIC connection went silent, typically
+	// because UDP is being silently dropped (MTU blackhole, firewall).
+	ErrorQUICIdleTimeout
+	// ErrorICMPUnreachable means the OS reported the UDP destination as
+	// unreachable (port/host/net unreachable), i.e. blocked UDP.
+	ErrorICMPUnreachable
+)
+
+// ClassifyError inspects an error returned from an HTTP/3 dial or send and
+// reports which typed QUIC/UDP failure mode it represents, if any.
+func ClassifyError(err error) ErrorClass {
+	if err == nil {
+		return ErrorOther
+	}
+
+	var versionErr *quic.VersionNegotiationError
+	if errors.As(err, &versionErr) {
+		return ErrorQUICVersionMismatch
+	}
+
+	var idleErr *quic.IdleTimeoutError
+	if errors.As(err, &idleErr) {
+		return ErrorQUICIdleTimeout
+	}
+
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		if opErr.Net == "udp" || strings.HasPrefix(opErr.Net, "udp") {
+			if sysErr, ok := opErr.Err.(*net.OpError); ok {
+				opErr = sysErr
+			}
+			msg := strings.ToLower(opErr.Error())
+			if strings.Contains(msg, "unreachable") {
+				return ErrorICMPUnreachable
+			}
+		}
+	}
+
+	return ErrorOther
+}
+
+// cacheEntry is the per-host remembered protocol.
+type cacheEntry struct {
+	host      string
+	protocol  Protocol
+	expiresAt time.Time
+}
+
+// Negotiator races an HTTP/3 dial against an HTTP/2 dial (with a small head
+// start for QUIC) on first contact with a host, and caches the winner in a
+// bounded LRU with TTL so subsequent sends don't pay the race cost.
+type Negotiator struct {
+	mu        sync.Mutex
+	entries   map[string]*list.Element
+	order     *list.List
+	maxSize   int
+	headStart time.Duration
+	ttl       time.Duration
+}
+
+// New creates a Negotiator. maxSize bounds the LRU cache of per-host
+// winners; headStart is how much of a lead HTTP/3's dial gets over HTTP/2's;
+// ttl is how long a cached winner is trusted before re-racing.
+func New(maxSize int, headStart, ttl time.Duration) *Negotiator {
+	if maxSize <= 0 {
+		maxSize = 256
+	}
+	if headStart <= 0 {
+		headStart = 250 * time.Millisecond
+	}
+	if ttl <= 0 {
+		ttl = 10 * time.Minute
+	}
+
+	return &Negotiator{
+		entries:   make(map[string]*list.Element),
+		order:     list.New(),
+		maxSize:   maxSize,
+		headStart: headStart,
+		ttl:       ttl,
+	}
+}
+
+// Lookup returns the cached protocol for host, if one hasn't expired.
+func (n *Negotiator) Lookup(host string) (Protocol, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	el, ok := n.entries[host]
+	if !ok {
+		return "", false
+	}
+
+	entry := el.Value.(*cacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		n.order.Remove(el)
+		delete(n.entries, host)
+		return "", false
+	}
+
+	n.order.MoveToFront(el)
+	return entry.protocol, true
+}
+
+// Remember records the winning protocol for host, evicting the least
+// recently used entry if the cache is full.
+func (n *Negotiator) Remember(host string, protocol Protocol) {
+	n.rememberWithTTL(host, protocol, n.ttl)
+}
+
+func (n *Negotiator) rememberWithTTL(host string, protocol Protocol, ttl time.Duration) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if el, ok := n.entries[host]; ok {
+		entry := el.Value.(*cacheEntry)
+		entry.protocol = protocol
+		entry.expiresAt = time.Now().Add(ttl)
+		n.order.MoveToFront(el)
+		return
+	}
+
+	entry := &cacheEntry{host: host, protocol: protocol, expiresAt: time.Now().Add(ttl)}
+	el := n.order.PushFront(entry)
+	n.entries[host] = el
+
+	if n.order.Len() > n.maxSize {
+		oldest := n.order.Back()
+		if oldest != nil {
+			n.order.Remove(oldest)
+			delete(n.entries, oldest.Value.(*cacheEntry).host)
+		}
+	}
+}
+
+// ObserveAltSvc inspects an Alt-Svc response header and, if it advertises
+// h3 support, upgrades the cached protocol for host back to ProtocolH3 so
+// the next send retries QUIC instead of waiting out a fixed cooldown.
+func (n *Negotiator) ObserveAltSvc(host, altSvc string) {
+	if altSvc == "" {
+		return
+	}
+	for _, entry := range strings.Split(altSvc, ",") {
+		entry = strings.TrimSpace(entry)
+		if strings.HasPrefix(entry, `h3=`) || strings.HasPrefix(entry, `h3-`) {
+			n.Remember(host, ProtocolH3)
+			return
+		}
+	}
+}
+
+// Race dials HTTP/3 and HTTP/2 concurrently (HTTP/3 gets a head start) and
+// returns whichever completes a successful exchange first. Either dial
+// function should perform a full round trip (dial + request) and return
+// nil on success. The loser's result is ignored once a winner is declared.
+func (n *Negotiator) Race(host string, dialH3, dialH2 func() error) (Protocol, error) {
+	type result struct {
+		protocol Protocol
+		err      error
+	}
+
+	results := make(chan result, 2)
+
+	go func() {
+		err := dialH3()
+		results <- result{ProtocolH3, err}
+	}()
+
+	go func() {
+		time.Sleep(n.headStart)
+		err := dialH2()
+		results <- result{ProtocolH2, err}
+	}()
+
+	var firstErr error
+	for i := 0; i < 2; i++ {
+		r := <-results
+		if r.err == nil {
+			n.Remember(host, r.protocol)
+			return r.protocol, nil
+		}
+		if firstErr == nil {
+			firstErr = r.err
+		}
+	}
+
+	return "", firstErr
+}
@@ -0,0 +1,130 @@
+package negotiator
+
+import (
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/quic-go/quic-go"
+)
+
+func TestClassifyError_ICMPUnreachable(t *testing.T) {
+	// Simulates blocked UDP: the OS reports the destination unreachable.
+	err := &net.OpError{
+		Op:  "write",
+		Net: "udp",
+		Err: &net.OpError{Err: errors.New("connect: network is unreachable")},
+	}
+
+	if got := ClassifyError(err); got != ErrorICMPUnreachable {
+		t.Fatalf("ClassifyError() = %v, want ErrorICMPUnreachable", got)
+	}
+}
+
+func TestClassifyError_QUICIdleTimeout(t *testing.T) {
+	// Simulates an MTU blackhole: QUIC packets go out but nothing ever
+	// comes back, so the connection eventually idles out rather than
+	// erroring immediately.
+	err := &quic.IdleTimeoutError{}
+
+	if got := ClassifyError(err); got != ErrorQUICIdleTimeout {
+		t.Fatalf("ClassifyError() = %v, want ErrorQUICIdleTimeout", got)
+	}
+}
+
+func TestClassifyError_Other(t *testing.T) {
+	if got := ClassifyError(errors.New("boom")); got != ErrorOther {
+		t.Fatalf("ClassifyError() = %v, want ErrorOther", got)
+	}
+}
+
+func TestNegotiator_RememberAndLookup(t *testing.T) {
+	n := New(8, 10*time.Millisecond, time.Hour)
+
+	if _, ok := n.Lookup("host-a"); ok {
+		t.Fatalf("expected no cached entry before Remember")
+	}
+
+	n.Remember("host-a", ProtocolH2)
+
+	got, ok := n.Lookup("host-a")
+	if !ok || got != ProtocolH2 {
+		t.Fatalf("Lookup() = (%v, %v), want (h2, true)", got, ok)
+	}
+}
+
+func TestNegotiator_EntryExpires(t *testing.T) {
+	n := New(8, 10*time.Millisecond, 20*time.Millisecond)
+	n.Remember("host-a", ProtocolH2)
+
+	time.Sleep(40 * time.Millisecond)
+
+	if _, ok := n.Lookup("host-a"); ok {
+		t.Fatalf("expected cached entry to have expired")
+	}
+}
+
+func TestNegotiator_ObserveAltSvcUpgradesToH3(t *testing.T) {
+	n := New(8, 10*time.Millisecond, time.Hour)
+
+	// Host previously fell back to HTTP/2 after a blocked-UDP failure.
+	n.Remember("host-a", ProtocolH2)
+
+	n.ObserveAltSvc("host-a", `h3=":443"; ma=3600, h2=":443"; ma=3600`)
+
+	got, ok := n.Lookup("host-a")
+	if !ok || got != ProtocolH3 {
+		t.Fatalf("Lookup() after Alt-Svc = (%v, %v), want (h3, true)", got, ok)
+	}
+}
+
+func TestNegotiator_ObserveAltSvcWithoutH3IsNoop(t *testing.T) {
+	n := New(8, 10*time.Millisecond, time.Hour)
+	n.Remember("host-a", ProtocolH2)
+
+	n.ObserveAltSvc("host-a", `h2=":443"; ma=3600`)
+
+	got, _ := n.Lookup("host-a")
+	if got != ProtocolH2 {
+		t.Fatalf("Lookup() = %v, want h2 (no h3 advertised)", got)
+	}
+}
+
+func TestNegotiator_RaceBlockedUDPFallsBackToH2(t *testing.T) {
+	n := New(8, 5*time.Millisecond, time.Hour)
+
+	dialH3 := func() error {
+		return &net.OpError{Op: "dial", Net: "udp", Err: errors.New("network is unreachable")}
+	}
+	dialH2 := func() error {
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+
+	winner, err := n.Race("host-a", dialH3, dialH2)
+	if err != nil {
+		t.Fatalf("Race() error = %v", err)
+	}
+	if winner != ProtocolH2 {
+		t.Fatalf("Race() winner = %v, want h2", winner)
+	}
+}
+
+func TestNegotiator_RaceH3WinsWithHeadStart(t *testing.T) {
+	n := New(8, 5*time.Millisecond, time.Hour)
+
+	dialH3 := func() error { return nil }
+	dialH2 := func() error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}
+
+	winner, err := n.Race("host-b", dialH3, dialH2)
+	if err != nil {
+		t.Fatalf("Race() error = %v", err)
+	}
+	if winner != ProtocolH3 {
+		t.Fatalf("Race() winner = %v, want h3", winner)
+	}
+}
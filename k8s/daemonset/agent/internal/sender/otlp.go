@@ -0,0 +1,479 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender/tlsauth"
+	"google.golang.org/protobuf/proto"
+
+	coll "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	collmetrics "go.opentelemetry.io/proto/otlp/collector/metrics/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	metricspb "go.opentelemetry.io/proto/otlp/metrics/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+)
+
+const (
+	otlpLogsPath    = "/v1/logs"
+	otlpMetricsPath = "/v1/metrics"
+)
+
+// drainIdleTimeout bounds how long a Shutdown's drain loop waits for one
+// more buffered message before concluding the buffer is empty and
+// returning early, instead of always blocking for the full shutdown-scoped
+// context deadline.
+const drainIdleTimeout = 250 * time.Millisecond
+
+// Sender is the common interface implemented by HTTP3Sender, OTLPSender and
+// RemoteWriteSender, selected at startup via OutputConfig.Protocol/Format.
+type Sender interface {
+	Send(data interface{}, metadata map[string]interface{}) error
+
+	// Shutdown performs a two-phase shutdown: phase 1 stops accepting new
+	// Send() calls and flushes whatever is already queued using ctx's
+	// deadline; phase 2 tears down transports. ctx should carry its own
+	// deadline (e.g. from config.AgentConfig.DrainTimeout) rather than one
+	// derived from the context passed to Send, so cancelling that context
+	// at shutdown doesn't abort the final flush before it has a chance to
+	// run.
+	Shutdown(ctx context.Context) error
+
+	Health() map[string]interface{}
+}
+
+// OTLPSender ships batches to an OpenTelemetry Collector (or any OTLP/HTTP
+// compatible backend) instead of the KillKrill py4web receiver. It is a
+// sibling to HTTP3Sender, selected via OutputConfig.Protocol == "otlphttp".
+type OTLPSender struct {
+	config   config.OutputConfig
+	client   *http.Client
+	dataType string
+	buffer   messageBuffer
+	stopCh   chan struct{}
+	wg       sync.WaitGroup
+	logger   *slog.Logger
+	tlsAuth  *tlsauth.Manager
+	closed   atomic.Bool
+}
+
+// NewOTLPSender creates a new OTLP/HTTP sender for the given data type
+// ("logs" or "metrics").
+func NewOTLPSender(cfg config.OutputConfig, dataType string, logger *slog.Logger) (*OTLPSender, error) {
+	if dataType != "logs" && dataType != "metrics" {
+		return nil, fmt.Errorf("otlp sender: unsupported data type %q", dataType)
+	}
+
+	tlsAuth, err := tlsauth.New(cfg.TLS, cfg.Auth, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS/auth: %w", err)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:     tlsAuth.TLSConfig(),
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	tlsAuth.Attach(nil, transport)
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+
+	logger = logger.With("component", fmt.Sprintf("otlp_sender_%s", dataType))
+
+	buffer, err := newMessageBuffer(cfg, dataType, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create send buffer: %w", err)
+	}
+
+	sender := &OTLPSender{
+		config:   cfg,
+		client:   client,
+		dataType: dataType,
+		buffer:   buffer,
+		stopCh:   make(chan struct{}),
+		logger:   logger,
+		tlsAuth:  tlsAuth,
+	}
+
+	sender.logger.Info("OTLP/HTTP sender initialized", "url", cfg.URL)
+
+	sender.wg.Add(1)
+	go sender.batchProcessor()
+
+	return sender, nil
+}
+
+// Send queues a message for export.
+func (s *OTLPSender) Send(data interface{}, metadata map[string]interface{}) error {
+	if s.closed.Load() {
+		return fmt.Errorf("otlp sender: closed, not accepting new messages")
+	}
+
+	msg := &Message{
+		Data:      data,
+		Timestamp: time.Now(),
+		Type:      s.dataType,
+		Metadata:  metadata,
+	}
+
+	if !s.buffer.push(msg) {
+		s.logger.Warn("Send buffer full, dropping message")
+		sentMessages.WithLabelValues(s.dataType, "dropped").Inc()
+		return fmt.Errorf("send buffer full")
+	}
+	return nil
+}
+
+// Shutdown stops accepting new Send() calls, flushes whatever is still
+// queued against ctx's deadline, then tears down the sender's transport.
+func (s *OTLPSender) Shutdown(ctx context.Context) error {
+	s.closed.Store(true)
+
+	close(s.stopCh)
+	s.wg.Wait()
+
+	s.drainRemaining(ctx)
+
+	s.buffer.close()
+	if transport, ok := s.client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
+	s.tlsAuth.Close()
+	return nil
+}
+
+// drainRemaining flushes any messages still sitting in the buffer that
+// batchProcessor's final select hadn't picked up before it returned, giving
+// up once ctx expires or the buffer has been idle for drainIdleTimeout.
+func (s *OTLPSender) drainRemaining(ctx context.Context) {
+	batch := make([]*Message, 0, s.config.BatchSize)
+	idle := time.NewTimer(drainIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case msg := <-s.buffer.messages():
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(drainIdleTimeout)
+
+			batch = append(batch, msg)
+			if len(batch) >= s.config.BatchSize {
+				s.sendBatch(ctx, batch)
+				batch = batch[:0]
+			}
+
+		case <-idle.C:
+			if len(batch) > 0 {
+				s.sendBatch(ctx, batch)
+			}
+			return
+
+		case <-ctx.Done():
+			if len(batch) > 0 {
+				s.sendBatch(ctx, batch)
+			}
+			return
+		}
+	}
+}
+
+func (s *OTLPSender) batchProcessor() {
+	defer s.wg.Done()
+
+	flushInterval, err := time.ParseDuration(s.config.FlushInterval)
+	if err != nil {
+		s.logger.Error("Invalid flush interval, using default", "flush_interval", s.config.FlushInterval, "default", "5s")
+		flushInterval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*Message, 0, s.config.BatchSize)
+
+	for {
+		select {
+		case msg := <-s.buffer.messages():
+			batch = append(batch, msg)
+			if len(batch) >= s.config.BatchSize {
+				s.sendBatch(context.Background(), batch)
+				batch = batch[:0]
+			}
+
+		case <-ticker.C:
+			if len(batch) > 0 {
+				s.sendBatch(context.Background(), batch)
+				batch = batch[:0]
+			}
+
+		case <-s.stopCh:
+			if len(batch) > 0 {
+				s.sendBatch(context.Background(), batch)
+			}
+			return
+		}
+	}
+}
+
+func (s *OTLPSender) sendBatch(ctx context.Context, batch []*Message) {
+	if len(batch) == 0 {
+		return
+	}
+
+	start := time.Now()
+	batchSize.WithLabelValues(s.dataType).Observe(float64(len(batch)))
+
+	var payload []byte
+	var path string
+	var err error
+
+	switch s.dataType {
+	case "logs":
+		payload, err = proto.Marshal(buildLogsRequest(batch))
+		path = otlpLogsPath
+	default:
+		payload, err = proto.Marshal(buildMetricsRequest(batch))
+		path = otlpMetricsPath
+	}
+
+	if err != nil {
+		s.logger.Error("Failed to marshal OTLP batch", "error", err)
+		sentMessages.WithLabelValues(s.dataType, "marshal_error").Add(float64(len(batch)))
+		return
+	}
+
+	protocol := "otlphttp"
+	if err := s.sendWithRetry(ctx, path, payload); err != nil {
+		s.logger.Error("Failed to export OTLP batch", "error", err)
+		sentMessages.WithLabelValues(s.dataType, "failed").Add(float64(len(batch)))
+		protocol = "failed"
+	} else {
+		ackBatch(batch)
+		sentMessages.WithLabelValues(s.dataType, "success").Add(float64(len(batch)))
+		sentBytes.WithLabelValues(s.dataType, "success").Add(float64(len(payload)))
+	}
+
+	sendDuration.WithLabelValues(s.dataType, protocol).Observe(time.Since(start).Seconds())
+}
+
+// sendWithRetry POSTs the protobuf payload, honoring OTLP retry semantics:
+// 429/503 respect Retry-After, other 4xx (except 408) are terminal, and the
+// total retry window is capped by RetryMaxElapsed.
+func (s *OTLPSender) sendWithRetry(ctx context.Context, path string, payload []byte) error {
+	backoff, err := time.ParseDuration(s.config.RetryBackoff)
+	if err != nil {
+		backoff = time.Second
+	}
+
+	maxElapsed, err := time.ParseDuration(s.config.RetryMaxElapsed)
+	if err != nil {
+		maxElapsed = 5 * time.Minute
+	}
+
+	deadline := time.Now().Add(maxElapsed)
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("otlp send aborted: %w", ctx.Err())
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("retry_max_elapsed exceeded: %w", lastErr)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL+path, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create OTLP request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		for key, value := range s.config.Headers {
+			req.Header.Set(key, value)
+		}
+		if authHeader := s.tlsAuth.AuthHeader(); authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("otlp request failed: %w", err)
+			time.Sleep(backoff * time.Duration(attempt+1))
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			return nil
+		}
+
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		lastErr = fmt.Errorf("otlp server returned %d: %s", resp.StatusCode, string(bodyBytes))
+
+		switch resp.StatusCode {
+		case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+			wait := parseRetryAfter(resp.Header.Get("Retry-After"), backoff*time.Duration(attempt+1))
+			time.Sleep(wait)
+		case http.StatusRequestTimeout:
+			time.Sleep(backoff * time.Duration(attempt+1))
+		default:
+			if resp.StatusCode >= 400 && resp.StatusCode < 500 {
+				return lastErr
+			}
+			time.Sleep(backoff * time.Duration(attempt+1))
+		}
+	}
+}
+
+// parseRetryAfter parses a Retry-After header, which may be given as a
+// number of seconds or an HTTP-date. Falls back to the provided default.
+func parseRetryAfter(header string, fallback time.Duration) time.Duration {
+	if header == "" {
+		return fallback
+	}
+
+	if seconds, err := strconv.Atoi(header); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if when, err := http.ParseTime(header); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+
+	return fallback
+}
+
+// resourceAttributes maps K8s pod/namespace/node metadata into OTel
+// semantic-convention resource attribute keys.
+func resourceAttributes(metadata map[string]interface{}) *resourcepb.Resource {
+	keyMap := map[string]string{
+		"pod_name":       "k8s.pod.name",
+		"pod_namespace":  "k8s.namespace.name",
+		"namespace":      "k8s.namespace.name",
+		"node_name":      "k8s.node.name",
+		"container_name": "k8s.container.name",
+		"cluster_name":   "k8s.cluster.name",
+	}
+
+	var attrs []*commonpb.KeyValue
+	for srcKey, otelKey := range keyMap {
+		value, ok := metadata[srcKey]
+		if !ok {
+			continue
+		}
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   otelKey,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%v", value)}},
+		})
+	}
+
+	return &resourcepb.Resource{Attributes: attrs}
+}
+
+func buildLogsRequest(batch []*Message) *coll.ExportLogsServiceRequest {
+	records := make([]*logspb.LogRecord, 0, len(batch))
+	for _, msg := range batch {
+		records = append(records, &logspb.LogRecord{
+			TimeUnixNano: uint64(msg.Timestamp.UnixNano()),
+			Body:         &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%v", msg.Data)}},
+		})
+	}
+
+	var resource *resourcepb.Resource
+	if len(batch) > 0 {
+		resource = resourceAttributes(batch[0].Metadata)
+	}
+
+	return &coll.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{
+			{
+				Resource: resource,
+				ScopeLogs: []*logspb.ScopeLogs{
+					{LogRecords: records},
+				},
+			},
+		},
+	}
+}
+
+func buildMetricsRequest(batch []*Message) *collmetrics.ExportMetricsServiceRequest {
+	var resource *resourcepb.Resource
+	if len(batch) > 0 {
+		resource = resourceAttributes(batch[0].Metadata)
+	}
+
+	metricsOut := make([]*metricspb.Metric, 0, len(batch))
+	for _, msg := range batch {
+		metricsOut = append(metricsOut, &metricspb.Metric{
+			Name: msg.Type,
+			Data: &metricspb.Metric_Gauge{
+				Gauge: &metricspb.Gauge{
+					DataPoints: []*metricspb.NumberDataPoint{metricDataPoint(msg)},
+				},
+			},
+		})
+	}
+
+	return &collmetrics.ExportMetricsServiceRequest{
+		ResourceMetrics: []*metricspb.ResourceMetrics{
+			{
+				Resource: resource,
+				ScopeMetrics: []*metricspb.ScopeMetrics{
+					{Metrics: metricsOut},
+				},
+			},
+		},
+	}
+}
+
+// metricDataPoint converts msg into the single NumberDataPoint backing its
+// Gauge metric: Data is coerced to a float64 sample the same way
+// remotewrite.go's sampleValue does, and Metadata becomes the point's
+// attributes.
+func metricDataPoint(msg *Message) *metricspb.NumberDataPoint {
+	attrs := make([]*commonpb.KeyValue, 0, len(msg.Metadata))
+	for k, v := range msg.Metadata {
+		attrs = append(attrs, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: fmt.Sprintf("%v", v)}},
+		})
+	}
+
+	return &metricspb.NumberDataPoint{
+		Attributes:   attrs,
+		TimeUnixNano: uint64(msg.Timestamp.UnixNano()),
+		Value:        &metricspb.NumberDataPoint_AsDouble{AsDouble: sampleValue(msg.Data)},
+	}
+}
+
+// Health returns the health status of the sender.
+func (s *OTLPSender) Health() map[string]interface{} {
+	bufferKind, bufferDepth, bufferMax := s.buffer.stats()
+	return map[string]interface{}{
+		"type":        "otlp_sender",
+		"data_type":   s.dataType,
+		"url":         s.config.URL,
+		"buffer_type": bufferKind,
+		"buffer_size": bufferDepth,
+		"buffer_cap":  bufferMax,
+		"protocol":    "otlphttp",
+	}
+}
@@ -0,0 +1,464 @@
+package sender
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender/tlsauth"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// RemoteWriteSender ships metrics directly to a Prometheus remote-write
+// endpoint (Prometheus, Mimir, Thanos, VictoriaMetrics, ...) instead of the
+// KillKrill py4web receiver or an OTel Collector. It is selected via
+// OutputConfig.Format == "remote_write" and is metrics-only.
+type RemoteWriteSender struct {
+	config  config.OutputConfig
+	client  *http.Client
+	buffer  messageBuffer
+	logger  *slog.Logger
+	tlsAuth *tlsauth.Manager
+	shards  []*rwShard
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+	closed  atomic.Bool
+
+	// drainCtx is set by Shutdown before stopCh is closed and read by
+	// shards afterward to bound their final send; the close(stopCh)
+	// happens-before relationship makes this safe without extra locking.
+	drainCtx context.Context
+}
+
+// NewRemoteWriteSender creates a new Prometheus remote-write sender.
+func NewRemoteWriteSender(cfg config.OutputConfig, logger *slog.Logger) (*RemoteWriteSender, error) {
+	tlsAuth, err := tlsauth.New(cfg.TLS, cfg.Auth, logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up TLS/auth: %w", err)
+	}
+
+	transport := &http.Transport{
+		TLSClientConfig:     tlsAuth.TLSConfig(),
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 10,
+		IdleConnTimeout:     90 * time.Second,
+	}
+	tlsAuth.Attach(nil, transport)
+
+	client := &http.Client{
+		Transport: transport,
+		Timeout:   30 * time.Second,
+	}
+
+	logger = logger.With("component", "remote_write_sender")
+
+	buffer, err := newMessageBuffer(cfg, "metrics", logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create send buffer: %w", err)
+	}
+
+	numShards := cfg.RemoteWrite.Shards
+	if numShards <= 0 {
+		numShards = 4
+	}
+
+	s := &RemoteWriteSender{
+		config:   cfg,
+		client:   client,
+		buffer:   buffer,
+		logger:   logger,
+		tlsAuth:  tlsAuth,
+		stopCh:   make(chan struct{}),
+		drainCtx: context.Background(),
+	}
+
+	for i := 0; i < numShards; i++ {
+		shard := newRWShard(i, s)
+		s.shards = append(s.shards, shard)
+		s.wg.Add(1)
+		go shard.run()
+	}
+
+	s.logger.Info("Remote-write sender initialized", "url", cfg.URL, "shards", numShards, "tenant_id", cfg.RemoteWrite.TenantID)
+
+	s.wg.Add(1)
+	go s.dispatchLoop()
+
+	return s, nil
+}
+
+// Send queues a metric for export. data is converted to a single float64
+// sample; metadata becomes the series' non-__name__ labels.
+func (s *RemoteWriteSender) Send(data interface{}, metadata map[string]interface{}) error {
+	if s.closed.Load() {
+		return fmt.Errorf("remote_write sender: closed, not accepting new messages")
+	}
+
+	msg := &Message{
+		Data:      data,
+		Timestamp: time.Now(),
+		Type:      "metrics",
+		Metadata:  metadata,
+	}
+
+	if !s.buffer.push(msg) {
+		s.logger.Warn("Send buffer full, dropping message")
+		sentMessages.WithLabelValues("metrics", "dropped").Inc()
+		return fmt.Errorf("send buffer full")
+	}
+	return nil
+}
+
+// dispatchLoop routes buffered messages to their shard by hashing the
+// series name+labels, so the same series is always handled by the same
+// shard's queue.
+func (s *RemoteWriteSender) dispatchLoop() {
+	defer s.wg.Done()
+
+	for {
+		select {
+		case msg := <-s.buffer.messages():
+			sample := &rwSample{ts: messageToTimeSeries(msg), ack: msg.Ack}
+			shard := s.shards[shardFor(sample.ts, len(s.shards))]
+			select {
+			case shard.in <- sample:
+			case <-s.stopCh:
+				return
+			}
+
+		case <-s.stopCh:
+			s.drainDispatch()
+			return
+		}
+	}
+}
+
+// drainDispatch forwards any messages still sitting in the buffer (queued
+// before Send() was closed off) to their shards before dispatchLoop exits,
+// giving up once the buffer has been idle for drainIdleTimeout.
+func (s *RemoteWriteSender) drainDispatch() {
+	idle := time.NewTimer(drainIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case msg := <-s.buffer.messages():
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(drainIdleTimeout)
+
+			sample := &rwSample{ts: messageToTimeSeries(msg), ack: msg.Ack}
+			shard := s.shards[shardFor(sample.ts, len(s.shards))]
+			shard.in <- sample
+
+		case <-idle.C:
+			return
+		}
+	}
+}
+
+// Shutdown stops accepting new Send() calls, lets dispatchLoop and every
+// shard drain whatever they already hold or had queued against ctx's
+// deadline, then tears down the sender's transport.
+func (s *RemoteWriteSender) Shutdown(ctx context.Context) error {
+	s.closed.Store(true)
+	s.drainCtx = ctx
+
+	close(s.stopCh)
+	s.wg.Wait()
+
+	s.buffer.close()
+	if transport, ok := s.client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
+	s.tlsAuth.Close()
+	return nil
+}
+
+// Health returns the health status of the sender.
+func (s *RemoteWriteSender) Health() map[string]interface{} {
+	bufferKind, bufferDepth, bufferMax := s.buffer.stats()
+	return map[string]interface{}{
+		"type":        "remote_write_sender",
+		"data_type":   "metrics",
+		"url":         s.config.URL,
+		"shards":      len(s.shards),
+		"tenant_id":   s.config.RemoteWrite.TenantID,
+		"buffer_type": bufferKind,
+		"buffer_size": bufferDepth,
+		"buffer_cap":  bufferMax,
+	}
+}
+
+// rwSample pairs one time series with the Ack of the Message it came from,
+// so send can acknowledge the originating WAL record once the batch it
+// ends up in is actually delivered, instead of losing track of it at the
+// Message-to-TimeSeries conversion.
+type rwSample struct {
+	ts  *prompb.TimeSeries
+	ack func()
+}
+
+// rwShard is one of the sender's per-tenant send queues: it batches
+// incoming series on its own timer and retries its own failed sends,
+// independent of every other shard.
+type rwShard struct {
+	id     int
+	sender *RemoteWriteSender
+	in     chan *rwSample
+}
+
+func newRWShard(id int, s *RemoteWriteSender) *rwShard {
+	capacity := s.config.BatchSize * 2
+	if capacity <= 0 {
+		capacity = 2000
+	}
+	return &rwShard{id: id, sender: s, in: make(chan *rwSample, capacity)}
+}
+
+func (sh *rwShard) run() {
+	defer sh.sender.wg.Done()
+
+	flushInterval, err := time.ParseDuration(sh.sender.config.FlushInterval)
+	if err != nil {
+		flushInterval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*rwSample, 0, sh.sender.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		sh.send(context.Background(), batch)
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case sample := <-sh.in:
+			batch = append(batch, sample)
+			if len(batch) >= sh.sender.config.BatchSize {
+				flush()
+			}
+
+		case <-ticker.C:
+			flush()
+
+		case <-sh.sender.stopCh:
+			flush()
+			sh.drainRemaining()
+			return
+		}
+	}
+}
+
+// drainRemaining flushes any series still sitting in sh.in that hadn't been
+// picked up into a batch before the sender's stopCh fired, using the
+// sender's shutdown-scoped drainCtx instead of context.Background() so the
+// final sends honor DrainTimeout.
+func (sh *rwShard) drainRemaining() {
+	batch := make([]*rwSample, 0, sh.sender.config.BatchSize)
+	idle := time.NewTimer(drainIdleTimeout)
+	defer idle.Stop()
+
+	for {
+		select {
+		case sample := <-sh.in:
+			if !idle.Stop() {
+				<-idle.C
+			}
+			idle.Reset(drainIdleTimeout)
+
+			batch = append(batch, sample)
+			if len(batch) >= sh.sender.config.BatchSize {
+				sh.send(sh.sender.drainCtx, batch)
+				batch = batch[:0]
+			}
+
+		case <-idle.C:
+			if len(batch) > 0 {
+				sh.send(sh.sender.drainCtx, batch)
+			}
+			return
+		}
+	}
+}
+
+func (sh *rwShard) send(ctx context.Context, batch []*rwSample) {
+	s := sh.sender
+	start := time.Now()
+
+	batchSize.WithLabelValues("metrics").Observe(float64(len(batch)))
+
+	series := make([]prompb.TimeSeries, len(batch))
+	for i, sample := range batch {
+		series[i] = *sample.ts
+	}
+
+	req := &prompb.WriteRequest{Timeseries: series}
+	payload, err := req.Marshal()
+	if err != nil {
+		s.logger.Error("Failed to marshal remote_write batch", "error", err, "shard", sh.id)
+		sentMessages.WithLabelValues("metrics", "marshal_error").Add(float64(len(batch)))
+		return
+	}
+
+	compressed := snappy.Encode(nil, payload)
+
+	if err := sh.sendWithRetry(ctx, compressed); err != nil {
+		s.logger.Error("Failed to send remote_write batch after retries", "error", err, "shard", sh.id)
+		sentMessages.WithLabelValues("metrics", "failed").Add(float64(len(batch)))
+	} else {
+		for _, sample := range batch {
+			if sample.ack != nil {
+				sample.ack()
+			}
+		}
+		sentMessages.WithLabelValues("metrics", "success").Add(float64(len(batch)))
+		sentBytes.WithLabelValues("metrics", "success").Add(float64(len(compressed)))
+	}
+
+	sendDuration.WithLabelValues("metrics", "remote_write").Observe(time.Since(start).Seconds())
+}
+
+// sendWithRetry POSTs the snappy-compressed WriteRequest, retrying with
+// exponential backoff plus jitter; a 429 is treated the same as any other
+// retryable failure since remote-write servers don't consistently send
+// Retry-After.
+func (sh *rwShard) sendWithRetry(ctx context.Context, payload []byte) error {
+	s := sh.sender
+
+	backoff, err := time.ParseDuration(s.config.RetryBackoff)
+	if err != nil {
+		backoff = time.Second
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= s.config.RetryAttempts; attempt++ {
+		if ctx.Err() != nil {
+			return fmt.Errorf("remote_write send aborted: %w", ctx.Err())
+		}
+
+		if attempt > 0 {
+			wait := backoff * time.Duration(1<<(attempt-1))
+			wait += time.Duration(rand.Int63n(int64(wait)/2 + 1))
+			time.Sleep(wait)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.config.URL, bytes.NewReader(payload))
+		if err != nil {
+			return fmt.Errorf("failed to create remote_write request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/x-protobuf")
+		req.Header.Set("Content-Encoding", "snappy")
+		req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+		if s.config.RemoteWrite.TenantID != "" {
+			req.Header.Set("X-Scope-OrgID", s.config.RemoteWrite.TenantID)
+		}
+		for key, value := range s.config.Headers {
+			req.Header.Set(key, value)
+		}
+		if authHeader := s.tlsAuth.AuthHeader(); authHeader != "" {
+			req.Header.Set("Authorization", authHeader)
+		}
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = fmt.Errorf("remote_write request failed: %w", err)
+			continue
+		}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			return nil
+		}
+
+		bodyBytes, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		lastErr = fmt.Errorf("remote_write server returned %d: %s", resp.StatusCode, string(bodyBytes))
+
+		// Don't retry on client errors other than 429, which remote-write
+		// servers use for backpressure rather than a permanent rejection.
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+			return lastErr
+		}
+	}
+
+	return lastErr
+}
+
+// shardFor picks a shard by hashing the series' name+labels, so the same
+// series is always routed to (and retried from) the same shard.
+func shardFor(ts *prompb.TimeSeries, numShards int) int {
+	h := fnv.New64a()
+	for _, l := range ts.Labels {
+		h.Write([]byte(l.Name))
+		h.Write([]byte{0})
+		h.Write([]byte(l.Value))
+		h.Write([]byte{0})
+	}
+	return int(h.Sum64() % uint64(numShards))
+}
+
+// messageToTimeSeries converts a generic Message into a single-sample
+// Prometheus time series: Type becomes the __name__ label, Metadata becomes
+// the remaining labels, and Data is coerced to the sample value.
+func messageToTimeSeries(msg *Message) *prompb.TimeSeries {
+	labels := []prompb.Label{{Name: "__name__", Value: sanitizeLabelValue(msg.Type)}}
+	for k, v := range msg.Metadata {
+		labels = append(labels, prompb.Label{Name: sanitizeLabelName(k), Value: fmt.Sprintf("%v", v)})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Name < labels[j].Name })
+
+	return &prompb.TimeSeries{
+		Labels: labels,
+		Samples: []prompb.Sample{{
+			Value:     sampleValue(msg.Data),
+			Timestamp: msg.Timestamp.UnixMilli(),
+		}},
+	}
+}
+
+func sampleValue(data interface{}) float64 {
+	switch v := data.(type) {
+	case float64:
+		return v
+	case float32:
+		return float64(v)
+	case int:
+		return float64(v)
+	case int64:
+		return float64(v)
+	default:
+		var f float64
+		fmt.Sscanf(fmt.Sprintf("%v", v), "%g", &f)
+		return f
+	}
+}
+
+func sanitizeLabelName(name string) string {
+	return invalidLabelChars.ReplaceAllString(name, "_")
+}
+
+func sanitizeLabelValue(name string) string {
+	return invalidLabelChars.ReplaceAllString(name, "_")
+}
@@ -0,0 +1,321 @@
+// Package tlsauth builds and hot-reloads the client TLS config and
+// Authorization header a sender uses to reach its output, so a rotated
+// certificate or refreshed token is picked up without restarting the agent.
+package tlsauth
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+	"github.com/quic-go/quic-go/http3"
+)
+
+// Manager owns the TLS config and Authorization header value for a single
+// output, rebuilding both whenever a watched credential file changes on
+// disk.
+type Manager struct {
+	tlsCfg config.OutputTLSConfig
+	auth   config.OutputAuthConfig
+	logger *slog.Logger
+
+	current    atomic.Pointer[tls.Config]
+	authHeader atomic.Pointer[string]
+
+	watcher *fsnotify.Watcher
+	stopCh  chan struct{}
+	wg      sync.WaitGroup
+
+	transportMu sync.Mutex
+	http3RT     *http3.RoundTripper
+	http1T      *http.Transport
+}
+
+// New builds a Manager for the given output's TLS and auth configuration,
+// loads the initial TLS config and Authorization header, and starts
+// watching their backing files for rotation. The returned Manager must be
+// closed with Close when the sender shuts down.
+func New(tlsCfg config.OutputTLSConfig, auth config.OutputAuthConfig, logger *slog.Logger) (*Manager, error) {
+	m := &Manager{
+		tlsCfg: tlsCfg,
+		auth:   auth,
+		logger: logger,
+		stopCh: make(chan struct{}),
+	}
+
+	if err := m.rebuildTLS(); err != nil {
+		return nil, fmt.Errorf("failed to build initial TLS config: %w", err)
+	}
+	if err := m.rebuildAuth(); err != nil {
+		return nil, fmt.Errorf("failed to build initial auth header: %w", err)
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create file watcher: %w", err)
+	}
+	m.watcher = watcher
+
+	for _, dir := range m.watchedDirs() {
+		if err := watcher.Add(dir); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", dir, err)
+		}
+	}
+
+	m.wg.Add(1)
+	go m.watchLoop()
+
+	return m, nil
+}
+
+// TLSConfig returns the current *tls.Config. Callers should re-read it
+// after a rotation rather than caching the pointer.
+func (m *Manager) TLSConfig() *tls.Config {
+	return m.current.Load()
+}
+
+// Attach hands the Manager the HTTP3 and HTTP1.1 transports it should keep
+// in sync: it installs the current TLS config into both immediately, and
+// installs the rebuilt config into both (under transportMu, so the two
+// fields are updated together) on every subsequent rotation.
+func (m *Manager) Attach(http3RT *http3.RoundTripper, http1T *http.Transport) {
+	m.transportMu.Lock()
+	m.http3RT = http3RT
+	m.http1T = http1T
+	m.transportMu.Unlock()
+
+	m.applyTLS()
+}
+
+// applyTLS swaps the current TLS config into both attached transports and
+// closes their existing connections so the next request dials fresh with
+// the new one. The HTTP1.1 transport only drops idle connections, so its
+// in-flight requests finish on the old config; closing the HTTP3 round
+// tripper closes all of its connections, including any in use.
+func (m *Manager) applyTLS() {
+	cfg := m.current.Load()
+	if cfg == nil {
+		return
+	}
+
+	m.transportMu.Lock()
+	http3RT, http1T := m.http3RT, m.http1T
+	if http3RT != nil {
+		http3RT.TLSClientConfig = cfg
+	}
+	if http1T != nil {
+		http1T.TLSClientConfig = cfg
+	}
+	m.transportMu.Unlock()
+
+	if http3RT != nil {
+		http3RT.Close()
+	}
+	if http1T != nil {
+		http1T.CloseIdleConnections()
+	}
+}
+
+// AuthHeader returns the current Authorization header value, or "" if no
+// auth is configured.
+func (m *Manager) AuthHeader() string {
+	if v := m.authHeader.Load(); v != nil {
+		return *v
+	}
+	return ""
+}
+
+// Close stops the file watcher.
+func (m *Manager) Close() error {
+	close(m.stopCh)
+	err := m.watcher.Close()
+	m.wg.Wait()
+	return err
+}
+
+// watchedDirs returns the set of directories containing files this Manager
+// reads, since fsnotify watches directories rather than individual files
+// (so atomic rename-based updates, as kubelet uses for projected secrets,
+// are observed).
+func (m *Manager) watchedDirs() []string {
+	seen := make(map[string]struct{})
+	var dirs []string
+	add := func(path string) {
+		if path == "" {
+			return
+		}
+		dir := filepath.Dir(path)
+		if _, ok := seen[dir]; ok {
+			return
+		}
+		seen[dir] = struct{}{}
+		dirs = append(dirs, dir)
+	}
+
+	add(m.tlsCfg.CAFile)
+	add(m.tlsCfg.CertFile)
+	add(m.tlsCfg.KeyFile)
+	switch m.auth.Type {
+	case "bearer":
+		add(m.auth.BearerTokenFile)
+	case "basic":
+		add(m.auth.Basic.UsernameFile)
+		add(m.auth.Basic.PasswordFile)
+	case "service_account":
+		add(m.auth.ServiceAccountTokenFile)
+	}
+
+	return dirs
+}
+
+// watchLoop rebuilds the TLS config or auth header whenever a relevant file
+// changes. Rebuild failures are logged and the previous value is kept in
+// place rather than leaving the sender without credentials.
+func (m *Manager) watchLoop() {
+	defer m.wg.Done()
+
+	for {
+		select {
+		case event, ok := <-m.watcher.Events:
+			if !ok {
+				return
+			}
+			if event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename|fsnotify.Remove) == 0 {
+				continue
+			}
+
+			if m.matchesAny(event.Name, m.tlsCfg.CAFile, m.tlsCfg.CertFile, m.tlsCfg.KeyFile) {
+				if err := m.rebuildTLS(); err != nil {
+					m.logger.Error("Failed to rebuild TLS config after file change", "error", err, "file", event.Name)
+				} else {
+					m.logger.Info("Reloaded output TLS config", "file", event.Name)
+				}
+			}
+
+			if m.matchesAny(event.Name, m.auth.BearerTokenFile, m.auth.Basic.UsernameFile, m.auth.Basic.PasswordFile, m.auth.ServiceAccountTokenFile) {
+				if err := m.rebuildAuth(); err != nil {
+					m.logger.Error("Failed to rebuild auth header after file change", "error", err, "file", event.Name)
+				} else {
+					m.logger.Info("Reloaded output auth credentials", "file", event.Name)
+				}
+			}
+
+		case err, ok := <-m.watcher.Errors:
+			if !ok {
+				return
+			}
+			m.logger.Error("File watcher error", "error", err)
+
+		case <-m.stopCh:
+			return
+		}
+	}
+}
+
+// matchesAny reports whether name refers to the same file as any of
+// candidates, comparing base names since fsnotify directory watches fire
+// with the directory entry's own path.
+func (m *Manager) matchesAny(name string, candidates ...string) bool {
+	base := filepath.Base(name)
+	for _, c := range candidates {
+		if c != "" && filepath.Base(c) == base {
+			return true
+		}
+	}
+	return false
+}
+
+// rebuildTLS loads the configured CA/cert/key files into a fresh
+// *tls.Config and stores it.
+func (m *Manager) rebuildTLS() error {
+	cfg := &tls.Config{
+		ServerName:         m.tlsCfg.ServerName,
+		InsecureSkipVerify: m.tlsCfg.InsecureSkipVerify,
+		NextProtos:         []string{"h3", "h2", "http/1.1"},
+	}
+
+	if m.tlsCfg.CAFile != "" {
+		pem, err := os.ReadFile(m.tlsCfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("failed to read ca_file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no certificates found in ca_file %s", m.tlsCfg.CAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if m.tlsCfg.CertFile != "" && m.tlsCfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(m.tlsCfg.CertFile, m.tlsCfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("failed to load client cert/key: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	m.current.Store(cfg)
+	m.applyTLS()
+	return nil
+}
+
+// rebuildAuth loads the configured credential files and stores the
+// resulting Authorization header value.
+func (m *Manager) rebuildAuth() error {
+	var header string
+
+	switch m.auth.Type {
+	case "":
+		// No auth configured; leave header empty.
+	case "bearer":
+		token, err := readTrimmed(m.auth.BearerTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read bearer_token_file: %w", err)
+		}
+		header = "Bearer " + token
+	case "basic":
+		username, err := readTrimmed(m.auth.Basic.UsernameFile)
+		if err != nil {
+			return fmt.Errorf("failed to read basic.username_file: %w", err)
+		}
+		password, err := readTrimmed(m.auth.Basic.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read basic.password_file: %w", err)
+		}
+		header = "Basic " + basicAuthValue(username, password)
+	case "service_account":
+		token, err := readTrimmed(m.auth.ServiceAccountTokenFile)
+		if err != nil {
+			return fmt.Errorf("failed to read service_account_token_file: %w", err)
+		}
+		header = "Bearer " + token
+	default:
+		return fmt.Errorf("unknown auth type %q", m.auth.Type)
+	}
+
+	m.authHeader.Store(&header)
+	return nil
+}
+
+func readTrimmed(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func basicAuthValue(username, password string) string {
+	return base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+}
@@ -0,0 +1,178 @@
+package tlsauth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
+)
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+}
+
+// newSelfSignedTLSServer starts an httptest server backed by its own
+// self-signed cert for "example.com", distinguished from any other
+// server's cert by serial. httptest.NewTLSServer alone reuses the same
+// hardcoded httptest.LocalhostCert for every server, so two servers built
+// that way are trusted by the same CA - useless for a test asserting that
+// trust in one server's cert doesn't extend to another's.
+func newSelfSignedTLSServer(t *testing.T, serial int64) *httptest.Server {
+	t.Helper()
+
+	priv, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "example.com"},
+		DNSNames:     []string{"example.com"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("failed to create self-signed certificate: %v", err)
+	}
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(okHandler))
+	server.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: priv}},
+	}
+	server.StartTLS()
+	return server
+}
+
+func testLogger() *slog.Logger {
+	return slog.New(slog.NewTextHandler(io.Discard, nil))
+}
+
+func writeCAFile(t *testing.T, path string, cert *x509.Certificate) {
+	t.Helper()
+	block := &pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}
+	if err := os.WriteFile(path, pem.EncodeToMemory(block), 0o600); err != nil {
+		t.Fatalf("failed to write CA file: %v", err)
+	}
+}
+
+func waitFor(t *testing.T, timeout time.Duration, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for time.Now().Before(deadline) {
+		if cond() {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	t.Fatalf("condition not met within %s", timeout)
+}
+
+func TestManager_TrustsConfiguredCA(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(okHandler))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCAFile(t, caFile, server.Certificate())
+
+	m, err := New(config.OutputTLSConfig{CAFile: caFile, ServerName: "example.com"}, config.OutputAuthConfig{}, testLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer m.Close()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: m.TLSConfig()}}
+	resp, err := client.Get(server.URL)
+	if err != nil {
+		t.Fatalf("request with trusted CA failed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+// TestManager_RotatesCAFileMidFlight simulates a CA rotation by rewriting
+// ca.pem to a different self-signed server's certificate while requests are
+// already flowing, and asserts the Manager picks up the new trust root
+// without the sender restarting.
+func TestManager_RotatesCAFileMidFlight(t *testing.T) {
+	serverA := newSelfSignedTLSServer(t, 1)
+	defer serverA.Close()
+	serverB := newSelfSignedTLSServer(t, 2)
+	defer serverB.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writeCAFile(t, caFile, serverA.Certificate())
+
+	m, err := New(config.OutputTLSConfig{CAFile: caFile, ServerName: "example.com"}, config.OutputAuthConfig{}, testLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer m.Close()
+
+	transport := &http.Transport{TLSClientConfig: m.TLSConfig()}
+	m.Attach(nil, transport)
+	client := &http.Client{Transport: transport}
+
+	if _, err := client.Get(serverA.URL); err != nil {
+		t.Fatalf("request to server A before rotation failed: %v", err)
+	}
+	if _, err := client.Get(serverB.URL); err == nil {
+		t.Fatalf("expected request to server B to fail before rotation (untrusted CA)")
+	}
+
+	writeCAFile(t, caFile, serverB.Certificate())
+
+	waitFor(t, 2*time.Second, func() bool {
+		resp, err := client.Get(serverB.URL)
+		if err != nil {
+			return false
+		}
+		resp.Body.Close()
+		return true
+	})
+
+	if _, err := client.Get(serverA.URL); err == nil {
+		t.Fatalf("expected request to server A to fail after rotation (no longer trusted)")
+	}
+}
+
+func TestManager_BearerTokenRotates(t *testing.T) {
+	tokenFile := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(tokenFile, []byte("first-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to write token file: %v", err)
+	}
+
+	m, err := New(config.OutputTLSConfig{}, config.OutputAuthConfig{Type: "bearer", BearerTokenFile: tokenFile}, testLogger())
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	defer m.Close()
+
+	if got, want := m.AuthHeader(), "Bearer first-token"; got != want {
+		t.Fatalf("AuthHeader() = %q, want %q", got, want)
+	}
+
+	if err := os.WriteFile(tokenFile, []byte("rotated-token\n"), 0o600); err != nil {
+		t.Fatalf("failed to rewrite token file: %v", err)
+	}
+
+	waitFor(t, 2*time.Second, func() bool {
+		return m.AuthHeader() == "Bearer rotated-token"
+	})
+}
@@ -0,0 +1,354 @@
+// Package wal implements a segmented, append-only write-ahead log used to
+// give the agent's output buffer Loki/Vector-style durability: when a
+// receiver is unreachable, batches spill here instead of being dropped, and
+// are replayed once the network recovers.
+package wal
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultSegmentBytes is the size at which an active segment is rotated.
+const defaultSegmentBytes int64 = 64 * 1024 * 1024
+
+const segmentPrefix = "segment-"
+const manifestName = "manifest.json"
+
+// manifest tracks the highest acknowledged position so a restart resumes
+// from the last unacked segment instead of replaying everything.
+type manifest struct {
+	AckedSegment int64 `json:"acked_segment"`
+	AckedOffset  int64 `json:"acked_offset"`
+}
+
+// WAL is a segmented on-disk log for a single data type (e.g. "logs").
+// Segments are named segment-<id>.log under Dir, newest segment is active,
+// and a manifest file tracks the last acknowledged read position.
+type WAL struct {
+	dir      string
+	mu       sync.Mutex
+	active   *os.File
+	activeID int64
+	size     int64
+	man      manifest
+}
+
+// Open creates or resumes a WAL rooted at dir, creating it if necessary.
+func Open(dir string) (*WAL, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("wal: create dir %s: %w", dir, err)
+	}
+
+	w := &WAL{dir: dir}
+
+	if err := w.loadManifest(); err != nil {
+		return nil, err
+	}
+
+	segmentID, err := latestSegmentID(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := w.openSegment(segmentID); err != nil {
+		return nil, err
+	}
+
+	return w, nil
+}
+
+func (w *WAL) segmentPath(id int64) string {
+	return filepath.Join(w.dir, fmt.Sprintf("%s%020d.log", segmentPrefix, id))
+}
+
+func (w *WAL) openSegment(id int64) error {
+	f, err := os.OpenFile(w.segmentPath(id), os.O_CREATE|os.O_RDWR|os.O_APPEND, 0o640)
+	if err != nil {
+		return fmt.Errorf("wal: open segment %d: %w", id, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("wal: stat segment %d: %w", id, err)
+	}
+
+	w.active = f
+	w.activeID = id
+	w.size = info.Size()
+	return nil
+}
+
+// Append writes a length-prefixed record and rotates the active segment
+// (fsyncing it first) once it exceeds defaultSegmentBytes.
+func (w *WAL) Append(record []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	var header [4]byte
+	binary.BigEndian.PutUint32(header[:], uint32(len(record)))
+
+	if _, err := w.active.Write(header[:]); err != nil {
+		return fmt.Errorf("wal: write header: %w", err)
+	}
+	if _, err := w.active.Write(record); err != nil {
+		return fmt.Errorf("wal: write record: %w", err)
+	}
+	w.size += int64(len(header) + len(record))
+
+	if w.size >= defaultSegmentBytes {
+		return w.rotate()
+	}
+	return nil
+}
+
+// rotate fsyncs and closes the active segment and opens the next one. Caller
+// must hold w.mu.
+func (w *WAL) rotate() error {
+	if err := w.active.Sync(); err != nil {
+		return fmt.Errorf("wal: fsync segment %d: %w", w.activeID, err)
+	}
+	if err := w.active.Close(); err != nil {
+		return fmt.Errorf("wal: close segment %d: %w", w.activeID, err)
+	}
+	return w.openSegment(w.activeID + 1)
+}
+
+// Depth returns the approximate number of unacknowledged bytes still on
+// disk across all segments.
+func (w *WAL) Depth() (int64, error) {
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, id := range segments {
+		if id < w.man.AckedSegment {
+			continue
+		}
+		info, err := os.Stat(w.segmentPath(id))
+		if err != nil {
+			continue
+		}
+		total += info.Size()
+	}
+	return total, nil
+}
+
+// Close fsyncs and closes the active segment.
+func (w *WAL) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.active.Sync(); err != nil {
+		return err
+	}
+	return w.active.Close()
+}
+
+func (w *WAL) loadManifest() error {
+	path := filepath.Join(w.dir, manifestName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return fmt.Errorf("wal: read manifest: %w", err)
+	}
+	return json.Unmarshal(data, &w.man)
+}
+
+// ack records the highest position that has been successfully sent, so a
+// subsequent Open resumes the reader from there rather than the start.
+func (w *WAL) ack(segmentID, offset int64) error {
+	w.man = manifest{AckedSegment: segmentID, AckedOffset: offset}
+
+	data, err := json.Marshal(w.man)
+	if err != nil {
+		return err
+	}
+
+	tmp := filepath.Join(w.dir, manifestName+".tmp")
+	if err := os.WriteFile(tmp, data, 0o640); err != nil {
+		return fmt.Errorf("wal: write manifest: %w", err)
+	}
+	return os.Rename(tmp, filepath.Join(w.dir, manifestName))
+}
+
+func latestSegmentID(dir string) (int64, error) {
+	ids, err := listSegments(dir)
+	if err != nil {
+		return 0, err
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+	return ids[len(ids)-1], nil
+}
+
+func listSegments(dir string) ([]int64, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("wal: list segments: %w", err)
+	}
+
+	var ids []int64
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasPrefix(e.Name(), segmentPrefix) {
+			continue
+		}
+		raw := strings.TrimSuffix(strings.TrimPrefix(e.Name(), segmentPrefix), ".log")
+		id, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			continue
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids, nil
+}
+
+// Reader replays unacknowledged records starting from the WAL's manifest
+// position, advancing and persisting the manifest as records are acked.
+type Reader struct {
+	w            *WAL
+	segments     []int64
+	segIdx       int
+	file         *os.File
+	br           *bufio.Reader
+	offset       int64
+	currentSegID int64
+}
+
+// NewReader returns a Reader resuming from the last acknowledged offset.
+func NewReader(w *WAL) (*Reader, error) {
+	segments, err := listSegments(w.dir)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{w: w, segments: segments}
+
+	// Skip fully-acked segments.
+	for r.segIdx < len(segments) && segments[r.segIdx] < w.man.AckedSegment {
+		r.segIdx++
+	}
+
+	if r.segIdx < len(segments) {
+		if err := r.openSegment(segments[r.segIdx]); err != nil {
+			return nil, err
+		}
+		if segments[r.segIdx] == w.man.AckedSegment {
+			if _, err := r.file.Seek(w.man.AckedOffset, io.SeekStart); err != nil {
+				return nil, err
+			}
+			r.offset = w.man.AckedOffset
+			r.br = bufio.NewReader(r.file)
+		}
+	}
+
+	return r, nil
+}
+
+func (r *Reader) openSegment(id int64) error {
+	f, err := os.Open(r.w.segmentPath(id))
+	if err != nil {
+		return fmt.Errorf("wal: open segment %d for read: %w", id, err)
+	}
+	r.file = f
+	r.br = bufio.NewReader(f)
+	r.currentSegID = id
+	r.offset = 0
+	return nil
+}
+
+// Next returns the next unread record, or io.EOF when the reader has caught
+// up with the active segment.
+func (r *Reader) Next() ([]byte, error) {
+	if r.file == nil {
+		if r.segIdx >= len(r.segments) {
+			return nil, io.EOF
+		}
+		if err := r.openSegment(r.segments[r.segIdx]); err != nil {
+			return nil, err
+		}
+	}
+
+	var header [4]byte
+	if _, err := io.ReadFull(r.br, header[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			// Exhausted this segment; advance unless it's the active one
+			// (re-listing first picks up a segment rotated in since).
+			if r.segIdx+1 >= len(r.segments) {
+				if segments, lerr := listSegments(r.w.dir); lerr == nil && len(segments) > len(r.segments) {
+					r.segments = segments
+					return r.Next()
+				}
+				r.file.Seek(r.offset, io.SeekStart)
+				r.br.Reset(r.file)
+				return nil, io.EOF
+			}
+			r.file.Close()
+			r.file = nil
+			r.segIdx++
+			if r.segIdx >= len(r.segments) {
+				return nil, io.EOF
+			}
+			return r.Next()
+		}
+		return nil, fmt.Errorf("wal: read header: %w", err)
+	}
+
+	length := binary.BigEndian.Uint32(header[:])
+	record := make([]byte, length)
+	if _, err := io.ReadFull(r.br, record); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			r.file.Seek(r.offset, io.SeekStart) // record still being appended
+			r.br.Reset(r.file)
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("wal: read record: %w", err)
+	}
+	r.offset += int64(len(header)) + int64(length)
+
+	return record, nil
+}
+
+// Ack persists the reader's current position as acknowledged.
+func (r *Reader) Ack() error {
+	return r.w.ack(r.currentSegID, r.offset)
+}
+
+// Position returns the position of the record Next() most recently
+// returned, for a caller that wants to ack it later (e.g. once a batch
+// containing several records has actually been delivered) via AckPosition
+// instead of Ack, without needing every record acked strictly in order.
+func (r *Reader) Position() (segmentID, offset int64) {
+	return r.currentSegID, r.offset
+}
+
+// AckPosition persists segmentID/offset as acknowledged, the same as Ack,
+// but for a specific previously-returned Position rather than the reader's
+// current one - letting a caller buffer several in-flight records and ack
+// whichever of them complete first, in any order.
+func (r *Reader) AckPosition(segmentID, offset int64) error {
+	return r.w.ack(segmentID, offset)
+}
+
+// Close releases the reader's open segment file, if any.
+func (r *Reader) Close() error {
+	if r.file != nil {
+		return r.file.Close()
+	}
+	return nil
+}
@@ -2,10 +2,11 @@ package main
 
 import (
 	"context"
-	"fmt"
 	"log"
+	"log/slog"
 	"os"
 	"os/signal"
+	"reflect"
 	"sync"
 	"syscall"
 	"time"
@@ -14,11 +15,12 @@ import (
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/config"
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/health"
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/kubernetes"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/logging"
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/logs"
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/metrics"
+	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/secrets"
 	"github.com/penguintechinc/killkrill/k8s/daemonset/agent/internal/sender"
 	"github.com/prometheus/client_golang/prometheus"
-	"github.com/sirupsen/logrus"
 )
 
 var (
@@ -57,16 +59,17 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// Setup logging
-	setupLogging(cfg)
+	// Build the root logger; every subsystem below is handed this logger
+	// (or a derived *slog.Logger) rather than reaching for a global.
+	logger := logging.New(cfg.Logging)
 
-	logrus.WithFields(logrus.Fields{
-		"version":    Version,
-		"git_commit": GitCommit,
-		"build_time": BuildTime,
-		"node":       cfg.Agent.NodeName,
-		"cluster":    cfg.Agent.ClusterName,
-	}).Info("KillKrill Agent starting")
+	logger.Info("KillKrill Agent starting",
+		"version", Version,
+		"git_commit", GitCommit,
+		"build_time", BuildTime,
+		"node", cfg.Agent.NodeName,
+		"cluster", cfg.Agent.ClusterName,
+	)
 
 	// Update Prometheus metrics
 	agentStartTime.WithLabelValues(cfg.Agent.NodeName, cfg.Agent.ClusterName).SetToCurrentTime()
@@ -80,21 +83,32 @@ func main() {
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
+	// Resolve any configured secret sources (license key, output TLS
+	// material, extra output headers) before building the senders below,
+	// so they pick up the resolved values on construction.
+	if err := secrets.ApplyOverrides(ctx, cfg); err != nil {
+		logger.Error("Failed to resolve configured secrets", "error", err)
+		os.Exit(1)
+	}
+
 	// Initialize Kubernetes client
 	k8sClient, err := kubernetes.NewClient(cfg)
 	if err != nil {
-		logrus.Fatalf("Failed to create Kubernetes client: %v", err)
+		logger.Error("Failed to create Kubernetes client", "error", err)
+		os.Exit(1)
 	}
 
-	// Initialize HTTP3/QUIC senders
-	logSender, err := sender.NewHTTP3Sender(cfg.Logs.Output, "logs")
+	// Initialize senders, honoring the per-output protocol selection
+	logSender, err := newSender(cfg.Logs.Output, "logs", logger)
 	if err != nil {
-		logrus.Fatalf("Failed to create log sender: %v", err)
+		logger.Error("Failed to create log sender", "error", err)
+		os.Exit(1)
 	}
 
-	metricsSender, err := sender.NewHTTP3Sender(cfg.Metrics.Output, "metrics")
+	metricsSender, err := newSender(cfg.Metrics.Output, "metrics", logger)
 	if err != nil {
-		logrus.Fatalf("Failed to create metrics sender: %v", err)
+		logger.Error("Failed to create metrics sender", "error", err)
+		os.Exit(1)
 	}
 
 	// Initialize collectors
@@ -105,7 +119,8 @@ func main() {
 	if cfg.Logs.Enabled {
 		logCollector, err := logs.NewCollector(cfg, k8sClient, logSender)
 		if err != nil {
-			logrus.Fatalf("Failed to create log collector: %v", err)
+			logger.Error("Failed to create log collector", "error", err)
+			os.Exit(1)
 		}
 		collectors = append(collectors, logCollector)
 	}
@@ -114,7 +129,8 @@ func main() {
 	if cfg.Metrics.Enabled {
 		metricsCollector, err := metrics.NewCollector(cfg, k8sClient, metricsSender)
 		if err != nil {
-			logrus.Fatalf("Failed to create metrics collector: %v", err)
+			logger.Error("Failed to create metrics collector", "error", err)
+			os.Exit(1)
 		}
 		collectors = append(collectors, metricsCollector)
 	}
@@ -125,7 +141,7 @@ func main() {
 	go func() {
 		defer wg.Done()
 		if err := healthServer.Start(ctx); err != nil {
-			logrus.Errorf("Health server error: %v", err)
+			logger.Error("Health server error", "error", err)
 		}
 	}()
 
@@ -135,28 +151,86 @@ func main() {
 		go func(collector collector.Collector) {
 			defer wg.Done()
 			if err := collector.Start(ctx); err != nil {
-				logrus.Errorf("Collector %s error: %v", collector.Name(), err)
+				logger.Error("Collector error", "collector", collector.Name(), "error", err)
 			}
 		}(c)
 	}
 
-	logrus.Info("KillKrill Agent started successfully")
+	// Watch the config file for SIGHUP/fsnotify-triggered reloads so a
+	// ConfigMap rollout doesn't have to restart every node's agent. Cert/key
+	// rotation is already handled independently by each sender's
+	// tlsauth.Manager file watch; what a reload applies here is everything
+	// else in Logs.Output/Metrics.Output - URL, Headers, Auth mode, retry
+	// and batching settings - by rebuilding that output's sender. sendersMu
+	// also guards the final Shutdown calls below against racing a reload.
+	var sendersMu sync.Mutex
+	lastLogOutput := cfg.Logs.Output
+	lastMetricsOutput := cfg.Metrics.Output
+
+	configFile := os.Getenv("CONFIG_FILE")
+	if configFile == "" {
+		configFile = "/etc/killkrill/config.yaml"
+	}
+	reloadCh, err := config.Watch(ctx, configFile)
+	if err != nil {
+		logger.Error("Failed to start config watcher", "error", err)
+	} else {
+		go func() {
+			for newCfg := range reloadCh {
+				logger.Info("Configuration reloaded", "config_file", configFile)
+
+				if err := secrets.ApplyOverrides(ctx, newCfg); err != nil {
+					logger.Error("Failed to resolve configured secrets for reloaded config", "error", err)
+					continue
+				}
+
+				if !reflect.DeepEqual(newCfg.Logs.Output, lastLogOutput) {
+					if err := reconfigureSender(&sendersMu, &logSender, newCfg.Logs.Output, "logs", logger); err != nil {
+						logger.Error("Failed to apply reloaded log output config", "error", err)
+					} else {
+						lastLogOutput = newCfg.Logs.Output
+					}
+				}
+
+				if !reflect.DeepEqual(newCfg.Metrics.Output, lastMetricsOutput) {
+					if err := reconfigureSender(&sendersMu, &metricsSender, newCfg.Metrics.Output, "metrics", logger); err != nil {
+						logger.Error("Failed to apply reloaded metrics output config", "error", err)
+					} else {
+						lastMetricsOutput = newCfg.Metrics.Output
+					}
+				}
+			}
+		}()
+	}
+
+	logger.Info("KillKrill Agent started successfully")
 
 	// Wait for shutdown signal
 	select {
 	case sig := <-sigChan:
-		logrus.WithField("signal", sig).Info("Received shutdown signal")
+		logger.Info("Received shutdown signal", "signal", sig)
 	case <-ctx.Done():
-		logrus.Info("Context cancelled")
+		logger.Info("Context cancelled")
 	}
 
 	// Graceful shutdown
-	logrus.Info("Shutting down KillKrill Agent...")
+	logger.Info("Shutting down KillKrill Agent...")
+
+	shutdownTimeout, err := time.ParseDuration(cfg.Agent.ShutdownTimeout)
+	if err != nil {
+		shutdownTimeout = 30 * time.Second
+	}
+	drainTimeout, err := time.ParseDuration(cfg.Agent.DrainTimeout)
+	if err != nil {
+		drainTimeout = 10 * time.Second
+	}
 
-	// Cancel context to signal all goroutines to stop
+	// Phase 1: report not-ready so Kubernetes stops routing new traffic to
+	// this pod, then cancel ctx to stop collectors and the health server.
+	healthServer.SetReady(false)
 	cancel()
 
-	// Wait for all collectors and servers to shutdown with timeout
+	// Wait for all collectors and servers to shut down with timeout
 	done := make(chan struct{})
 	go func() {
 		wg.Wait()
@@ -165,50 +239,70 @@ func main() {
 
 	select {
 	case <-done:
-		logrus.Info("All components shut down successfully")
-	case <-time.After(30 * time.Second):
-		logrus.Warn("Shutdown timeout exceeded, forcing exit")
+		logger.Info("All components shut down successfully")
+	case <-time.After(shutdownTimeout):
+		logger.Warn("Shutdown timeout exceeded, proceeding to sender drain anyway")
 	}
 
-	// Close senders
-	logSender.Close()
-	metricsSender.Close()
+	// Phase 2: drain each sender's outstanding batches against a fresh,
+	// shutdown-scoped context rather than ctx, which is already canceled,
+	// so the final flush gets its own DrainTimeout instead of returning
+	// immediately. Only after draining is it safe to tear down transports.
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), drainTimeout)
+	defer drainCancel()
 
-	logrus.Info("KillKrill Agent shutdown complete")
+	sendersMu.Lock()
+	if err := logSender.Shutdown(drainCtx); err != nil {
+		logger.Error("Log sender shutdown error", "error", err)
+	}
+	if err := metricsSender.Shutdown(drainCtx); err != nil {
+		logger.Error("Metrics sender shutdown error", "error", err)
+	}
+	sendersMu.Unlock()
+
+	logger.Info("KillKrill Agent shutdown complete")
 }
 
-func setupLogging(cfg *config.Config) {
-	// Set log level
-	level, err := logrus.ParseLevel(cfg.Logging.Level)
+// reconfigureSender rebuilds *current from newOutput, draining the
+// existing sender first so at most one sender ever has the output's
+// on-disk WAL buffer open at once. Used when a config reload changes an
+// output's URL, Headers, Auth, or other settings newSender reads, since
+// nothing short of a full rebuild applies a new Protocol/Format/URL to an
+// already-constructed sender.
+func reconfigureSender(mu *sync.Mutex, current *sender.Sender, newOutput config.OutputConfig, dataType string, logger *slog.Logger) error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	old := *current
+	drainCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := old.Shutdown(drainCtx); err != nil {
+		logger.Warn("Error draining sender before reload", "data_type", dataType, "error", err)
+	}
+
+	rebuilt, err := newSender(newOutput, dataType, logger)
 	if err != nil {
-		logrus.Warnf("Invalid log level '%s', using info", cfg.Logging.Level)
-		level = logrus.InfoLevel
-	}
-	logrus.SetLevel(level)
-
-	// Set log format
-	switch cfg.Logging.Format {
-	case "json":
-		logrus.SetFormatter(&logrus.JSONFormatter{
-			TimestampFormat: time.RFC3339Nano,
-			FieldMap: logrus.FieldMap{
-				logrus.FieldKeyTime:  "timestamp",
-				logrus.FieldKeyLevel: "level",
-				logrus.FieldKeyMsg:   "message",
-			},
-		})
-	default:
-		logrus.SetFormatter(&logrus.TextFormatter{
-			FullTimestamp:   true,
-			TimestampFormat: time.RFC3339,
-		})
+		return err
 	}
 
-	// Add common fields
-	logrus.SetReportCaller(false)
+	*current = rebuilt
+	logger.Info("Rebuilt sender for reloaded output config", "data_type", dataType, "url", newOutput.URL)
+	return nil
+}
+
+// newSender builds the configured sender.Sender implementation for an
+// output, defaulting to the HTTP3/py4web sender when no protocol is set.
+// Metrics outputs with Format == "remote_write" bypass Protocol entirely
+// and ship straight to a Prometheus remote-write endpoint.
+func newSender(cfg config.OutputConfig, dataType string, logger *slog.Logger) (sender.Sender, error) {
+	if dataType == "metrics" && cfg.Format == "remote_write" {
+		return sender.NewRemoteWriteSender(cfg, logger)
+	}
 
-	// Add structured fields if configured
-	if len(cfg.Logging.Fields) > 0 {
-		logrus.WithFields(logrus.Fields(cfg.Logging.Fields))
+	switch cfg.Protocol {
+	case "otlphttp":
+		return sender.NewOTLPSender(cfg, dataType, logger)
+	default:
+		return sender.NewHTTP3Sender(cfg, dataType, logger)
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// SetupWebhookWithManager registers this type's validating webhook with
+// mgr. Nothing in this repository snapshot constructs a manager yet (see
+// controllers/killkrill_multitenancy.go's CacheOptionsForNamespaces for
+// the same situation) - this is the call a future main.go should make
+// once one exists.
+func (r *KillKrill) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(r).
+		WithValidator(&killKrillValidator{}).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-killkrill-penguintech-io-v1-killkrill,mutating=false,failurePolicy=fail,sideEffects=None,groups=killkrill.penguintech.io,resources=killkrillclusters;killkrillclusters/status,verbs=update,versions=v1,name=vkillkrill.kb.io,admissionReviewVersions=v1
+
+// killKrillValidator enforces PhaseTransitions on Status.Phase updates, so
+// nothing - a buggy reconcile, a hand-edited status patch - can jump the
+// KillKrill's lifecycle state machine, e.g. straight from Starting to
+// Updating, skipping Running.
+type killKrillValidator struct{}
+
+var _ webhook.CustomValidator = &killKrillValidator{}
+
+// ValidateCreate allows any initial state; PhaseTransitions only
+// constrains how Status.Phase may change on an existing object.
+func (v *killKrillValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate rejects a Status.Phase change that isn't listed in
+// PhaseTransitions for the object's current phase.
+func (v *killKrillValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	oldKillKrill, ok := oldObj.(*KillKrill)
+	if !ok {
+		return nil, fmt.Errorf("expected a KillKrill for oldObj, got %T", oldObj)
+	}
+	newKillKrill, ok := newObj.(*KillKrill)
+	if !ok {
+		return nil, fmt.Errorf("expected a KillKrill for newObj, got %T", newObj)
+	}
+
+	oldPhase, newPhase := oldKillKrill.Status.Phase, newKillKrill.Status.Phase
+	if oldPhase == newPhase {
+		return nil, nil
+	}
+
+	for _, allowed := range PhaseTransitions[oldPhase] {
+		if allowed == newPhase {
+			return nil, nil
+		}
+	}
+
+	return nil, fmt.Errorf("invalid status.phase transition from %q to %q", oldPhase, newPhase)
+}
+
+// ValidateDelete allows deletion regardless of phase; reconcileDeletion
+// is what actually moves Status.Phase to Terminating.
+func (v *killKrillValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
@@ -5,7 +5,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
@@ -15,17 +18,33 @@ import (
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+	"github.com/penguintechinc/killkrill/k8s/operator/license"
+	"github.com/penguintechinc/killkrill/k8s/operator/status"
 )
 
 // KillKrillReconciler reconciles a KillKrill object
 type KillKrillReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// License validates and caches Spec.License.Key's entitlements; shared
+	// across every KillKrill this reconciler handles.
+	License *license.Client
+
+	// WatchedNamespaces restricts reconciliation to a fixed set of
+	// namespaces, for running one operator instance against many tenants'
+	// KillKrill CRs while scoping RBAC per tenant. It is meant to be paired
+	// with a label-selector/namespace-scoped cache built with
+	// CacheOptionsForNamespaces at manager construction time; this check
+	// is defense in depth for the case where the cache wasn't scoped.
+	// Empty means "watch every namespace".
+	WatchedNamespaces []string
 }
 
 //+kubebuilder:rbac:groups=killkrill.penguintech.io,resources=killkrillclusters,verbs=get;list;watch;create;update;patch;delete
@@ -38,12 +57,22 @@ type KillKrillReconciler struct {
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=networking.k8s.io,resources=ingresses,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
 func (r *KillKrillReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	logger := log.FromContext(ctx)
 
+	// Ignore CRs outside WatchedNamespaces. Normally the manager's cache is
+	// already scoped to these namespaces (see CacheOptionsForNamespaces),
+	// so this rarely triggers - it's a second layer of tenancy isolation
+	// in case the cache wasn't configured that way.
+	if !r.namespaceWatched(req.Namespace) {
+		return ctrl.Result{}, nil
+	}
+
 	// Fetch the KillKrill instance
 	killkrill := &killkrillv1.KillKrill{}
 	err := r.Get(ctx, req.NamespacedName, killkrill)
@@ -58,11 +87,43 @@ func (r *KillKrillReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		return ctrl.Result{}, err
 	}
 
+	// If the object is being deleted, run Spec.DeletePipeline before
+	// letting the finalizer go so owned resources can be garbage-collected.
+	if !killkrill.DeletionTimestamp.IsZero() {
+		return r.reconcileDeletion(ctx, killkrill)
+	}
+
+	// Make sure the finalizer is present before anything else runs, so a
+	// delete requested mid-reconcile is guaranteed to hit reconcileDeletion.
+	if !controllerutil.ContainsFinalizer(killkrill, killkrillFinalizer) {
+		controllerutil.AddFinalizer(killkrill, killkrillFinalizer)
+		if err := r.Update(ctx, killkrill); err != nil {
+			logger.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
 	// Set default values if not specified
 	r.setDefaults(killkrill)
 
+	// Validate the license and enforce its entitlements (replica caps,
+	// receiver HPA sizing, per-tenant rate-limit ConfigMap) before anything
+	// is created, so a tenant never briefly gets more than they're
+	// licensed for.
+	if _, err := r.reconcileLicense(ctx, killkrill); err != nil {
+		logger.Error(err, "Failed to reconcile license")
+		if err := r.Status().Update(ctx, killkrill); err != nil {
+			logger.Error(err, "Failed to update license status")
+		}
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+
 	// Update status phase
-	killkrill.Status.Phase = "Reconciling"
+	// A KillKrill that is already Running or Updating keeps its phase:
+	// PhaseTransitions has no move from either back to Starting.
+	if killkrill.Status.Phase == "" || killkrill.Status.Phase == killkrillv1.PhaseError {
+		killkrill.Status.Phase = killkrillv1.PhaseStarting
+	}
 	if err := r.Status().Update(ctx, killkrill); err != nil {
 		logger.Error(err, "Failed to update KillKrill status")
 		return ctrl.Result{}, err
@@ -71,29 +132,91 @@ func (r *KillKrillReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// Reconcile infrastructure components
 	if err := r.reconcileInfrastructure(ctx, killkrill); err != nil {
 		logger.Error(err, "Failed to reconcile infrastructure")
+		if statusErr := r.markPhaseError(ctx, killkrill); statusErr != nil {
+			logger.Error(statusErr, "Failed to update KillKrill status")
+		}
 		return ctrl.Result{RequeueAfter: time.Minute * 2}, err
 	}
 
+	// Run Spec.ConfigurePipeline once infrastructure is up and before
+	// applications are rolled out (schema migrations, index template
+	// seeding, Grafana provisioning, ...).
+	if !killkrill.Status.ConfigurePipelineComplete {
+		outcome, err := r.advancePipeline(ctx, killkrill, killkrill.Spec.ConfigurePipeline, killkrill.Status.ConfigurePipeline, "configure")
+		if err != nil {
+			logger.Error(err, "Failed to run configure pipeline")
+			return ctrl.Result{RequeueAfter: time.Minute}, err
+		}
+
+		killkrill.Status.ConfigurePipeline = outcome.statuses
+		if outcome.done {
+			killkrill.Status.ConfigurePipelineComplete = true
+		}
+		if err := r.Status().Update(ctx, killkrill); err != nil {
+			logger.Error(err, "Failed to update configure pipeline status")
+			return ctrl.Result{}, err
+		}
+
+		if !outcome.done {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+	}
+
+	// Gate moving on to applications on Spec.UpgradeStrategy's phased
+	// rollout: while an upgrade is in progress this holds at whichever
+	// phase (Elasticsearch, PostgreSQL, Workers, Receivers) hasn't yet
+	// passed its health probe, or sits paused on UpgradePauseAnnotation.
+	upgradeDone, upgradeRequeue, err := r.advanceUpgrade(ctx, killkrill)
+	if err != nil {
+		logger.Error(err, "Failed to advance upgrade")
+		return ctrl.Result{RequeueAfter: time.Minute}, err
+	}
+	if err := r.Status().Update(ctx, killkrill); err != nil {
+		logger.Error(err, "Failed to update upgrade status")
+		return ctrl.Result{}, err
+	}
+	if !upgradeDone {
+		return ctrl.Result{RequeueAfter: upgradeRequeue}, nil
+	}
+
 	// Reconcile KillKrill applications
 	if err := r.reconcileApplications(ctx, killkrill); err != nil {
 		logger.Error(err, "Failed to reconcile applications")
+		if statusErr := r.markPhaseError(ctx, killkrill); statusErr != nil {
+			logger.Error(statusErr, "Failed to update KillKrill status")
+		}
 		return ctrl.Result{RequeueAfter: time.Minute * 2}, err
 	}
 
 	// Reconcile monitoring components
 	if err := r.reconcileMonitoring(ctx, killkrill); err != nil {
 		logger.Error(err, "Failed to reconcile monitoring")
+		if statusErr := r.markPhaseError(ctx, killkrill); statusErr != nil {
+			logger.Error(statusErr, "Failed to update KillKrill status")
+		}
 		return ctrl.Result{RequeueAfter: time.Minute * 2}, err
 	}
 
 	// Reconcile ingress/networking
 	if err := r.reconcileNetworking(ctx, killkrill); err != nil {
 		logger.Error(err, "Failed to reconcile networking")
+		if statusErr := r.markPhaseError(ctx, killkrill); statusErr != nil {
+			logger.Error(statusErr, "Failed to update KillKrill status")
+		}
+		return ctrl.Result{RequeueAfter: time.Minute * 2}, err
+	}
+
+	// Reconcile scheduled backups
+	if err := r.reconcileBackups(ctx, killkrill); err != nil {
+		logger.Error(err, "Failed to reconcile backups")
+		if statusErr := r.markPhaseError(ctx, killkrill); statusErr != nil {
+			logger.Error(statusErr, "Failed to update KillKrill status")
+		}
 		return ctrl.Result{RequeueAfter: time.Minute * 2}, err
 	}
 
-	// Update status to Ready
-	killkrill.Status.Phase = "Ready"
+	// Update status to Running
+	killkrill.Status.Phase = killkrillv1.PhaseRunning
 	r.updateEndpoints(killkrill)
 	if err := r.Status().Update(ctx, killkrill); err != nil {
 		logger.Error(err, "Failed to update KillKrill status")
@@ -101,7 +224,13 @@ func (r *KillKrillReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	logger.Info("Successfully reconciled KillKrill")
-	return ctrl.Result{RequeueAfter: time.Minute * 10}, nil
+
+	// Readiness itself is no longer tracked by polling here: the status
+	// subsystem's per-kind watchers (see status.SetupAll) update
+	// Status.ResourceBundle the moment an owned resource's state changes,
+	// and Owns() below re-enqueues this Reconcile on the same events. The
+	// long RequeueAfter is just a drift safety net.
+	return ctrl.Result{RequeueAfter: time.Hour}, nil
 }
 
 // setDefaults sets default values for the KillKrill spec
@@ -117,15 +246,15 @@ func (r *KillKrillReconciler) setDefaults(killkrill *killkrillv1.KillKrill) {
 	if killkrill.Spec.Infrastructure.PostgreSQL.Username == "" {
 		killkrill.Spec.Infrastructure.PostgreSQL.Username = "killkrill"
 	}
-	if killkrill.Spec.Infrastructure.PostgreSQL.StorageSize == "" {
-		killkrill.Spec.Infrastructure.PostgreSQL.StorageSize = "10Gi"
+	if killkrill.Spec.Infrastructure.PostgreSQL.StorageSize.IsZero() {
+		killkrill.Spec.Infrastructure.PostgreSQL.StorageSize = resource.MustParse("10Gi")
 	}
 
-	if killkrill.Spec.Infrastructure.Redis.MemoryLimit == "" {
-		killkrill.Spec.Infrastructure.Redis.MemoryLimit = "1Gi"
+	if killkrill.Spec.Infrastructure.Redis.MemoryLimit.IsZero() {
+		killkrill.Spec.Infrastructure.Redis.MemoryLimit = resource.MustParse("1Gi")
 	}
-	if killkrill.Spec.Infrastructure.Redis.StorageSize == "" {
-		killkrill.Spec.Infrastructure.Redis.StorageSize = "5Gi"
+	if killkrill.Spec.Infrastructure.Redis.StorageSize.IsZero() {
+		killkrill.Spec.Infrastructure.Redis.StorageSize = resource.MustParse("5Gi")
 	}
 
 	if killkrill.Spec.Infrastructure.Elasticsearch.MasterNodes == 0 {
@@ -134,18 +263,18 @@ func (r *KillKrillReconciler) setDefaults(killkrill *killkrillv1.KillKrill) {
 	if killkrill.Spec.Infrastructure.Elasticsearch.DataNodes == 0 {
 		killkrill.Spec.Infrastructure.Elasticsearch.DataNodes = 2
 	}
-	if killkrill.Spec.Infrastructure.Elasticsearch.HeapSize == "" {
-		killkrill.Spec.Infrastructure.Elasticsearch.HeapSize = "8g"
+	if killkrill.Spec.Infrastructure.Elasticsearch.HeapSize.IsZero() {
+		killkrill.Spec.Infrastructure.Elasticsearch.HeapSize = resource.MustParse("8Gi")
 	}
-	if killkrill.Spec.Infrastructure.Elasticsearch.StorageSize == "" {
-		killkrill.Spec.Infrastructure.Elasticsearch.StorageSize = "50Gi"
+	if killkrill.Spec.Infrastructure.Elasticsearch.StorageSize.IsZero() {
+		killkrill.Spec.Infrastructure.Elasticsearch.StorageSize = resource.MustParse("50Gi")
 	}
 
 	if killkrill.Spec.Infrastructure.Prometheus.Retention == "" {
 		killkrill.Spec.Infrastructure.Prometheus.Retention = "15d"
 	}
-	if killkrill.Spec.Infrastructure.Prometheus.StorageSize == "" {
-		killkrill.Spec.Infrastructure.Prometheus.StorageSize = "20Gi"
+	if killkrill.Spec.Infrastructure.Prometheus.StorageSize.IsZero() {
+		killkrill.Spec.Infrastructure.Prometheus.StorageSize = resource.MustParse("20Gi")
 	}
 
 	// Set default application settings
@@ -172,33 +301,102 @@ func (r *KillKrillReconciler) setDefaults(killkrill *killkrillv1.KillKrill) {
 
 // reconcileInfrastructure reconciles infrastructure components
 func (r *KillKrillReconciler) reconcileInfrastructure(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	logger := log.FromContext(ctx)
+
 	// PostgreSQL
 	if err := r.reconcilePostgreSQL(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentPostgreSQL, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile PostgreSQL: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentPostgreSQL, killkrillv1.PhaseRunning)
 
 	// Redis
 	if err := r.reconcileRedis(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentRedis, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile Redis: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentRedis, killkrillv1.PhaseRunning)
 
 	// Elasticsearch
 	if err := r.reconcileElasticsearch(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentElasticsearch, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile Elasticsearch: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentElasticsearch, killkrillv1.PhaseRunning)
 
 	// Prometheus
 	if err := r.reconcilePrometheus(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentPrometheus, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile Prometheus: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentPrometheus, killkrillv1.PhaseRunning)
 
 	return nil
 }
 
 // reconcilePostgreSQL creates/updates PostgreSQL StatefulSet and Service
 func (r *KillKrillReconciler) reconcilePostgreSQL(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	cfg := killkrill.Spec.Infrastructure.PostgreSQL
+
+	if cfg.Mode == killkrillv1.DatastoreModeExternal {
+		return r.reconcileExternalDatastore(ctx, killkrill, "postgres", cfg.ConnectionRef)
+	}
+
 	logger := log.FromContext(ctx)
 
+	container := corev1.Container{
+		Name:  "postgres",
+		Image: "postgres:15-alpine",
+		Env: []corev1.EnvVar{
+			{
+				Name:  "POSTGRES_DB",
+				Value: cfg.Database,
+			},
+			{
+				Name:  "POSTGRES_USER",
+				Value: cfg.Username,
+			},
+			{
+				Name: "POSTGRES_PASSWORD",
+				ValueFrom: &corev1.EnvVarSource{
+					SecretKeyRef: &corev1.SecretKeySelector{
+						LocalObjectReference: corev1.LocalObjectReference{
+							Name: "killkrill-postgres-secret",
+						},
+						Key: "password",
+					},
+				},
+			},
+		},
+		Ports: []corev1.ContainerPort{
+			{
+				ContainerPort: 5432,
+				Name:          "postgres",
+			},
+		},
+		VolumeMounts: []corev1.VolumeMount{
+			{
+				Name:      "postgres-storage",
+				MountPath: "/var/lib/postgresql/data",
+			},
+		},
+		Resources: corev1.ResourceRequirements{
+			Limits: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("2Gi"),
+				corev1.ResourceCPU:    resource.MustParse("1000m"),
+			},
+			Requests: corev1.ResourceList{
+				corev1.ResourceMemory: resource.MustParse("1Gi"),
+				corev1.ResourceCPU:    resource.MustParse("500m"),
+			},
+		},
+	}
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{container},
+	}
+	applyPodExtras(&podSpec, &podSpec.Containers[0], cfg.PodExtras)
+
 	// Create StatefulSet
 	sts := &appsv1.StatefulSet{
 		ObjectMeta: metav1.ObjectMeta{
@@ -219,57 +417,7 @@ func (r *KillKrillReconciler) reconcilePostgreSQL(ctx context.Context, killkrill
 						"app": "killkrill-postgres",
 					},
 				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "postgres",
-							Image: "postgres:15-alpine",
-							Env: []corev1.EnvVar{
-								{
-									Name:  "POSTGRES_DB",
-									Value: killkrill.Spec.Infrastructure.PostgreSQL.Database,
-								},
-								{
-									Name:  "POSTGRES_USER",
-									Value: killkrill.Spec.Infrastructure.PostgreSQL.Username,
-								},
-								{
-									Name: "POSTGRES_PASSWORD",
-									ValueFrom: &corev1.EnvVarSource{
-										SecretKeyRef: &corev1.SecretKeySelector{
-											LocalObjectReference: corev1.LocalObjectReference{
-												Name: "killkrill-postgres-secret",
-											},
-											Key: "password",
-										},
-									},
-								},
-							},
-							Ports: []corev1.ContainerPort{
-								{
-									ContainerPort: 5432,
-									Name:          "postgres",
-								},
-							},
-							VolumeMounts: []corev1.VolumeMount{
-								{
-									Name:      "postgres-storage",
-									MountPath: "/var/lib/postgresql/data",
-								},
-							},
-							Resources: corev1.ResourceRequirements{
-								Limits: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("2Gi"),
-									corev1.ResourceCPU:    resource.MustParse("1000m"),
-								},
-								Requests: corev1.ResourceList{
-									corev1.ResourceMemory: resource.MustParse("1Gi"),
-									corev1.ResourceCPU:    resource.MustParse("500m"),
-								},
-							},
-						},
-					},
-				},
+				Spec: podSpec,
 			},
 			VolumeClaimTemplates: []corev1.PersistentVolumeClaim{
 				{
@@ -282,7 +430,7 @@ func (r *KillKrillReconciler) reconcilePostgreSQL(ctx context.Context, killkrill
 						},
 						Resources: corev1.ResourceRequirements{
 							Requests: corev1.ResourceList{
-								corev1.ResourceStorage: resource.MustParse(killkrill.Spec.Infrastructure.PostgreSQL.StorageSize),
+								corev1.ResourceStorage: cfg.StorageSize,
 							},
 						},
 					},
@@ -334,11 +482,25 @@ func (r *KillKrillReconciler) reconcilePostgreSQL(ctx context.Context, killkrill
 		return err
 	}
 
+	if cfg.Mode == killkrillv1.DatastoreModeHybrid {
+		return r.reconcileExternalDatastore(ctx, killkrill, "postgres", cfg.ConnectionRef)
+	}
+
 	return nil
 }
 
 // reconcileRedis creates/updates Redis StatefulSet and Service
 func (r *KillKrillReconciler) reconcileRedis(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	cfg := killkrill.Spec.Infrastructure.Redis
+	if cfg.Mode == killkrillv1.DatastoreModeExternal || cfg.Mode == killkrillv1.DatastoreModeHybrid {
+		if err := r.reconcileExternalDatastore(ctx, killkrill, "redis", cfg.ConnectionRef); err != nil {
+			return err
+		}
+		if cfg.Mode == killkrillv1.DatastoreModeExternal {
+			return nil
+		}
+	}
+
 	// Similar implementation to PostgreSQL but for Redis
 	// This would create Redis StatefulSet with persistence
 	return nil
@@ -346,6 +508,16 @@ func (r *KillKrillReconciler) reconcileRedis(ctx context.Context, killkrill *kil
 
 // reconcileElasticsearch creates/updates Elasticsearch cluster
 func (r *KillKrillReconciler) reconcileElasticsearch(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	cfg := killkrill.Spec.Infrastructure.Elasticsearch
+	if cfg.Mode == killkrillv1.DatastoreModeExternal || cfg.Mode == killkrillv1.DatastoreModeHybrid {
+		if err := r.reconcileExternalDatastore(ctx, killkrill, "elasticsearch", cfg.ConnectionRef); err != nil {
+			return err
+		}
+		if cfg.Mode == killkrillv1.DatastoreModeExternal {
+			return nil
+		}
+	}
+
 	// Implementation for Elasticsearch cluster with master and data nodes
 	// This would create multiple StatefulSets for different node types
 	return nil
@@ -353,49 +525,122 @@ func (r *KillKrillReconciler) reconcileElasticsearch(ctx context.Context, killkr
 
 // reconcilePrometheus creates/updates Prometheus StatefulSet and Service
 func (r *KillKrillReconciler) reconcilePrometheus(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	cfg := killkrill.Spec.Infrastructure.Prometheus
+	if cfg.Mode == killkrillv1.DatastoreModeExternal || cfg.Mode == killkrillv1.DatastoreModeHybrid {
+		if err := r.reconcileExternalDatastore(ctx, killkrill, "prometheus", cfg.ConnectionRef); err != nil {
+			return err
+		}
+		if cfg.Mode == killkrillv1.DatastoreModeExternal {
+			return nil
+		}
+	}
+
 	// Implementation for Prometheus with configuration and storage
 	return nil
 }
 
 // reconcileApplications reconciles KillKrill application components
 func (r *KillKrillReconciler) reconcileApplications(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	logger := log.FromContext(ctx)
+
 	// Log Receiver
 	if err := r.reconcileLogReceiver(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentLogReceiver, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile log receiver: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentLogReceiver, killkrillv1.PhaseRunning)
 
 	// Metrics Receiver
 	if err := r.reconcileMetricsReceiver(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentMetricsReceiver, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile metrics receiver: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentMetricsReceiver, killkrillv1.PhaseRunning)
 
 	// Log Worker
 	if err := r.reconcileLogWorker(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentLogWorker, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile log worker: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentLogWorker, killkrillv1.PhaseRunning)
 
 	// Metrics Worker
 	if err := r.reconcileMetricsWorker(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentMetricsWorker, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile metrics worker: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentMetricsWorker, killkrillv1.PhaseRunning)
 
 	// Manager
 	if err := r.reconcileManager(ctx, killkrill); err != nil {
+		r.recordComponentPhase(logger, killkrill, ComponentManager, killkrillv1.PhaseError)
 		return fmt.Errorf("failed to reconcile manager: %w", err)
 	}
+	r.recordComponentPhase(logger, killkrill, ComponentManager, killkrillv1.PhaseRunning)
 
 	return nil
 }
 
+// recordComponentPhase calls SetComponentPhase and logs rather than
+// returning an error, since the only failure mode - an unrecognized
+// component name - can't happen with the hardcoded Component* constants
+// reconcileInfrastructure and reconcileApplications pass in.
+func (r *KillKrillReconciler) recordComponentPhase(logger logr.Logger, killkrill *killkrillv1.KillKrill, component string, phase killkrillv1.Phase) {
+	if err := SetComponentPhase(killkrill, component, phase); err != nil {
+		logger.Error(err, "Failed to record component phase", "component", component, "phase", phase)
+	}
+}
+
 // reconcileLogReceiver creates/updates log receiver deployment
 func (r *KillKrillReconciler) reconcileLogReceiver(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	logReceiver := killkrill.Spec.Applications.LogReceiver
+
+	container := corev1.Container{
+		Name:  "log-receiver",
+		Image: "killkrill/log-receiver:latest",
+		Ports: []corev1.ContainerPort{
+			{ContainerPort: 8081, Name: "http"},
+			{ContainerPort: 10000, Name: "syslog", Protocol: corev1.ProtocolUDP},
+		},
+		Env: append([]corev1.EnvVar{
+			{
+				Name:  "LICENSE_KEY",
+				Value: killkrill.Spec.License.Key,
+			},
+			{
+				Name:  "PRODUCT_NAME",
+				Value: killkrill.Spec.License.Product,
+			},
+		}, logReceiver.Env...),
+		EnvFrom: []corev1.EnvFromSource{
+			{
+				ConfigMapRef: &corev1.ConfigMapEnvSource{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: "killkrill-ratelimit",
+					},
+				},
+			},
+		},
+		VolumeMounts: logReceiver.VolumeMounts,
+		Resources:    logReceiver.Resources,
+	}
+
+	podSpec := corev1.PodSpec{
+		Containers: []corev1.Container{container},
+		SecurityContext: &corev1.PodSecurityContext{
+			RunAsNonRoot: &[]bool{false}[0], // XDP requires root
+		},
+	}
+	applyPodExtras(&podSpec, &podSpec.Containers[0], logReceiver.PodExtras)
+
 	deployment := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:      "killkrill-log-receiver",
 			Namespace: killkrill.Namespace,
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: &killkrill.Spec.Applications.LogReceiver.Replicas,
+			Replicas: &logReceiver.Replicas,
+			Strategy: componentRolloutStrategy(killkrill, "logReceiver"),
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": "killkrill-log-receiver",
@@ -407,32 +652,7 @@ func (r *KillKrillReconciler) reconcileLogReceiver(ctx context.Context, killkril
 						"app": "killkrill-log-receiver",
 					},
 				},
-				Spec: corev1.PodSpec{
-					Containers: []corev1.Container{
-						{
-							Name:  "log-receiver",
-							Image: "killkrill/log-receiver:latest",
-							Ports: []corev1.ContainerPort{
-								{ContainerPort: 8081, Name: "http"},
-								{ContainerPort: 10000, Name: "syslog", Protocol: corev1.ProtocolUDP},
-							},
-							Env: []corev1.EnvVar{
-								{
-									Name:  "LICENSE_KEY",
-									Value: killkrill.Spec.License.Key,
-								},
-								{
-									Name:  "PRODUCT_NAME",
-									Value: killkrill.Spec.License.Product,
-								},
-							},
-							Resources: killkrill.Spec.Applications.LogReceiver.Resources,
-						},
-					},
-					SecurityContext: &corev1.PodSecurityContext{
-						RunAsNonRoot: &[]bool{false}[0], // XDP requires root
-					},
-				},
+				Spec: podSpec,
 			},
 		},
 	}
@@ -475,6 +695,64 @@ func (r *KillKrillReconciler) reconcileNetworking(ctx context.Context, killkrill
 	return nil
 }
 
+// reconcileDeletion runs Spec.DeletePipeline, in order, on a KillKrill
+// marked for deletion, and only removes killkrillFinalizer once every step
+// has reported Succeeded (or Failed with ContinueOnFailure set).
+func (r *KillKrillReconciler) reconcileDeletion(ctx context.Context, killkrill *killkrillv1.KillKrill) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	if !controllerutil.ContainsFinalizer(killkrill, killkrillFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	if killkrill.Status.Phase != killkrillv1.PhaseTerminating {
+		killkrill.Status.Phase = killkrillv1.PhaseTerminating
+		if err := r.Status().Update(ctx, killkrill); err != nil {
+			logger.Error(err, "Failed to update KillKrill status")
+			return ctrl.Result{}, err
+		}
+	}
+
+	outcome, err := r.advancePipeline(ctx, killkrill, killkrill.Spec.DeletePipeline, killkrill.Status.DeletePipeline, "delete")
+	if err != nil {
+		logger.Error(err, "Failed to run delete pipeline")
+		return ctrl.Result{RequeueAfter: time.Second * 30}, err
+	}
+
+	killkrill.Status.DeletePipeline = outcome.statuses
+	if err := r.Status().Update(ctx, killkrill); err != nil {
+		logger.Error(err, "Failed to update delete pipeline status")
+		return ctrl.Result{}, err
+	}
+
+	if !outcome.done {
+		if outcome.requeue {
+			return ctrl.Result{RequeueAfter: time.Second * 10}, nil
+		}
+		logger.Info("Delete pipeline blocked on a failed step, holding finalizer", "killkrill", killkrill.Name)
+		return ctrl.Result{}, nil
+	}
+
+	controllerutil.RemoveFinalizer(killkrill, killkrillFinalizer)
+	if err := r.Update(ctx, killkrill); err != nil {
+		logger.Error(err, "Failed to remove finalizer")
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// markPhaseError moves killkrill.Status.Phase to PhaseError and persists
+// it, so a reconcile failure is visible on the object itself (not just in
+// the controller's logs) until the next successful reconcile moves it
+// back to Starting, Updating or Running.
+func (r *KillKrillReconciler) markPhaseError(ctx context.Context, killkrill *killkrillv1.KillKrill) error {
+	killkrill.Status.Phase = killkrillv1.PhaseError
+	setCondition(killkrill, "Ready", metav1.ConditionFalse, "ReconcileError", "the last reconcile attempt failed")
+	setCondition(killkrill, "Degraded", metav1.ConditionTrue, "ReconcileError", "the last reconcile attempt failed")
+	return r.Status().Update(ctx, killkrill)
+}
+
 // updateEndpoints updates the status with service endpoints
 func (r *KillKrillReconciler) updateEndpoints(killkrill *killkrillv1.KillKrill) {
 	killkrill.Status.Endpoints.ManagerURL = fmt.Sprintf("http://killkrill-manager.%s.svc.cluster.local:8080", killkrill.Namespace)
@@ -484,14 +762,35 @@ func (r *KillKrillReconciler) updateEndpoints(killkrill *killkrillv1.KillKrill)
 	killkrill.Status.Endpoints.AlertManagerURL = fmt.Sprintf("http://killkrill-alertmanager.%s.svc.cluster.local:9093", killkrill.Namespace)
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller with the Manager. Owns() filtered
+// by status.StatusRelevant means an owned resource's spec changes (a
+// Generation bump) no longer re-enqueue this Reconcile; status and
+// metadata-only updates still do. It is the same filter status.SetupAll's
+// watchers use.
 func (r *KillKrillReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&killkrillv1.KillKrill{}).
-		Owns(&appsv1.Deployment{}).
-		Owns(&appsv1.StatefulSet{}).
-		Owns(&corev1.Service{}).
-		Owns(&corev1.ConfigMap{}).
-		Owns(&networkingv1.Ingress{}).
+		Owns(&appsv1.Deployment{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&appsv1.StatefulSet{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&corev1.Service{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&corev1.PersistentVolumeClaim{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&corev1.ConfigMap{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&networkingv1.Ingress{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&batchv1.Job{}, builder.WithPredicates(status.StatusRelevant)).
+		Owns(&autoscalingv2.HorizontalPodAutoscaler{}, builder.WithPredicates(status.StatusRelevant)).
 		Complete(r)
-}
\ No newline at end of file
+}
+
+// namespaceWatched reports whether ns should be reconciled, given
+// r.WatchedNamespaces. An empty WatchedNamespaces watches every namespace.
+func (r *KillKrillReconciler) namespaceWatched(ns string) bool {
+	if len(r.WatchedNamespaces) == 0 {
+		return true
+	}
+	for _, watched := range r.WatchedNamespaces {
+		if watched == ns {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,130 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+)
+
+// reconcileExternalDatastore is the DatastoreModeExternal/Hybrid path for
+// PostgreSQL, Redis, Elasticsearch and Prometheus: instead of (or, for
+// Hybrid, in addition to) creating a StatefulSet/Service, it probes
+// ref's Secret for reachability, records the result in Status.Conditions,
+// and republishes the DSN as the normalized killkrill-<component>-conn
+// Secret so application deployments don't need to know whether they're
+// talking to a Managed or an External datastore.
+func (r *KillKrillReconciler) reconcileExternalDatastore(ctx context.Context, killkrill *killkrillv1.KillKrill, component string, ref killkrillv1.ConnectionRef) error {
+	if ref.SecretName == "" {
+		return fmt.Errorf("%s is in External/Hybrid mode but connectionRef.secretName is empty", component)
+	}
+
+	dsnKey := ref.DSNKey
+	if dsnKey == "" {
+		dsnKey = "dsn"
+	}
+
+	source := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Name: ref.SecretName, Namespace: killkrill.Namespace}, source); err != nil {
+		return fmt.Errorf("failed to get connection secret %s for %s: %w", ref.SecretName, component, err)
+	}
+
+	dsn, ok := source.Data[dsnKey]
+	if !ok {
+		return fmt.Errorf("connection secret %s for %s has no %q key", ref.SecretName, component, dsnKey)
+	}
+
+	reachable, message := probeDatastoreDSN(ctx, component, string(dsn))
+	setDatastoreCondition(killkrill, component, reachable, message)
+
+	conn := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("killkrill-%s-conn", component),
+			Namespace: killkrill.Namespace,
+		},
+		Data: map[string][]byte{
+			"dsn": dsn,
+		},
+	}
+	if err := controllerutil.SetControllerReference(killkrill, conn, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference on %s connection secret: %w", component, err)
+	}
+
+	if err := r.Create(ctx, conn); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create %s connection secret: %w", component, err)
+		}
+		if err := r.Update(ctx, conn); err != nil {
+			return fmt.Errorf("failed to update %s connection secret: %w", component, err)
+		}
+	}
+
+	return nil
+}
+
+// probeDatastoreDSN dials the host:port parsed out of dsn as a lightweight
+// reachability check. It deliberately doesn't speak each datastore's wire
+// protocol - a plain TCP probe is already useful signal for "is this RDS
+// endpoint's security group even open to us", and it works the same way
+// for every component without a driver per datastore.
+func probeDatastoreDSN(ctx context.Context, component, dsn string) (bool, string) {
+	u, err := url.Parse(dsn)
+	if err != nil || u.Host == "" {
+		return false, fmt.Sprintf("%s connection string could not be parsed for a reachability probe", component)
+	}
+
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", u.Host)
+	if err != nil {
+		return false, fmt.Sprintf("%s at %s is unreachable: %v", component, u.Host, err)
+	}
+	conn.Close()
+
+	return true, fmt.Sprintf("%s at %s is reachable", component, u.Host)
+}
+
+// setDatastoreCondition records an External/Hybrid-mode component's
+// reachability as a "<Component>Reachable" condition in Status.Conditions,
+// alongside the aggregate "Ready" condition the status subsystem maintains.
+func setDatastoreCondition(killkrill *killkrillv1.KillKrill, component string, reachable bool, message string) {
+	condType := strings.ToUpper(component[:1]) + component[1:] + "Reachable"
+
+	condStatus := metav1.ConditionFalse
+	if reachable {
+		condStatus = metav1.ConditionTrue
+	}
+
+	condition := metav1.Condition{
+		Type:               condType,
+		Status:             condStatus,
+		Reason:             "ConnectivityProbe",
+		Message:            message,
+		ObservedGeneration: killkrill.Generation,
+	}
+
+	for i, existing := range killkrill.Status.Conditions {
+		if existing.Type != condType {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		killkrill.Status.Conditions[i] = condition
+		return
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	killkrill.Status.Conditions = append(killkrill.Status.Conditions, condition)
+}
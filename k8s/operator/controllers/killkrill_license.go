@@ -0,0 +1,224 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+	"github.com/penguintechinc/killkrill/k8s/operator/license"
+)
+
+// reconcileLicense validates killkrill's Spec.License.Key (through r.License,
+// which caches the result for its TTL), records the outcome in
+// Status.License, and, once validated, enforces the returned entitlements:
+// it clamps the receiver/worker replica requests to the licensed cap,
+// records a LicenseQuotaExceeded condition when a clamp actually kicked in,
+// sizes the receiver HPAs, and republishes the per-tenant rate-limit
+// ConfigMap the receivers read. It returns the validated entitlements so
+// callers can use them for the same reconcile without validating twice.
+func (r *KillKrillReconciler) reconcileLicense(ctx context.Context, killkrill *killkrillv1.KillKrill) (license.Entitlements, error) {
+	entitlements, err := r.License.Validate(ctx, killkrill.Spec.License.Server, killkrill.Spec.License.Product, killkrill.Spec.License.Key)
+	if err != nil {
+		killkrill.Status.License = killkrillv1.LicenseStatus{
+			Valid:   false,
+			Message: err.Error(),
+		}
+		setCondition(killkrill, "LicenseValid", metav1.ConditionFalse, "ValidationFailed", err.Error())
+		return license.Entitlements{}, fmt.Errorf("failed to validate license: %w", err)
+	}
+
+	killkrill.Status.License = killkrillv1.LicenseStatus{
+		Valid:              true,
+		Message:            "license validated",
+		MaxEPS:             entitlements.MaxEPS,
+		MaxIngestGBPerDay:  entitlements.MaxIngestGBPerDay,
+		MaxRetainedTenants: entitlements.MaxRetainedTenants,
+		ValidatedAt:        metav1.Now(),
+	}
+	setCondition(killkrill, "LicenseValid", metav1.ConditionTrue, "Validated", "license validated")
+
+	r.enforceLicenseQuota(killkrill, entitlements)
+
+	if err := r.reconcileRateLimitConfigMap(ctx, killkrill, entitlements); err != nil {
+		return entitlements, fmt.Errorf("failed to reconcile rate-limit configmap: %w", err)
+	}
+
+	if err := r.reconcileReceiverHPAs(ctx, killkrill, entitlements); err != nil {
+		return entitlements, fmt.Errorf("failed to reconcile receiver HPAs: %w", err)
+	}
+
+	return entitlements, nil
+}
+
+// enforceLicenseQuota clamps Spec.Applications' receiver/worker replica
+// counts to entitlements' caps. Like setDefaults, this mutates the in-memory
+// Spec only - it is re-applied on every reconcile rather than persisted, so
+// a tenant can never get more replicas than their license allows even if
+// the Spec on the apiserver still asks for more. A LicenseQuotaExceeded
+// condition is set so the clamp is visible instead of silent.
+func (r *KillKrillReconciler) enforceLicenseQuota(killkrill *killkrillv1.KillKrill, entitlements license.Entitlements) {
+	exceeded := false
+	exceeded = clampReplicas(&killkrill.Spec.Applications.LogReceiver.Replicas, entitlements.MaxReceiverReplicas) || exceeded
+	exceeded = clampReplicas(&killkrill.Spec.Applications.MetricsReceiver.Replicas, entitlements.MaxReceiverReplicas) || exceeded
+	exceeded = clampReplicas(&killkrill.Spec.Applications.LogWorker.Replicas, entitlements.MaxWorkerReplicas) || exceeded
+	exceeded = clampReplicas(&killkrill.Spec.Applications.MetricsWorker.Replicas, entitlements.MaxWorkerReplicas) || exceeded
+
+	if exceeded {
+		setCondition(killkrill, "LicenseQuotaExceeded", metav1.ConditionTrue, "ReplicasCapped",
+			"one or more components requested more replicas than the license allows; requests were capped at the licensed maximum")
+		return
+	}
+
+	setCondition(killkrill, "LicenseQuotaExceeded", metav1.ConditionFalse, "WithinQuota",
+		"requested replicas are within the licensed quota")
+}
+
+// clampReplicas caps *replicas at max, reporting whether it had to. max <= 0
+// means the license places no cap on this component.
+func clampReplicas(replicas *int32, max int32) bool {
+	if max > 0 && *replicas > max {
+		*replicas = max
+		return true
+	}
+	return false
+}
+
+// reconcileRateLimitConfigMap writes the per-tenant rate-limit ConfigMap the
+// log/metrics receivers read their entitlements from at startup.
+func (r *KillKrillReconciler) reconcileRateLimitConfigMap(ctx context.Context, killkrill *killkrillv1.KillKrill, entitlements license.Entitlements) error {
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "killkrill-ratelimit",
+			Namespace: killkrill.Namespace,
+		},
+		Data: map[string]string{
+			"max_eps":               strconv.Itoa(entitlements.MaxEPS),
+			"max_ingest_gb_per_day": strconv.Itoa(entitlements.MaxIngestGBPerDay),
+			"max_retained_tenants":  strconv.Itoa(entitlements.MaxRetainedTenants),
+		},
+	}
+
+	if err := controllerutil.SetControllerReference(killkrill, cm, r.Scheme); err != nil {
+		return fmt.Errorf("failed to set owner reference on rate-limit configmap: %w", err)
+	}
+
+	if err := r.Create(ctx, cm); err != nil {
+		if !errors.IsAlreadyExists(err) {
+			return fmt.Errorf("failed to create rate-limit configmap: %w", err)
+		}
+		if err := r.Update(ctx, cm); err != nil {
+			return fmt.Errorf("failed to update rate-limit configmap: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// reconcileReceiverHPAs sizes the log/metrics receiver Deployments' HPAs to
+// entitlements' licensed cap, so autoscaling can't grow a tenant past what
+// its license allows.
+func (r *KillKrillReconciler) reconcileReceiverHPAs(ctx context.Context, killkrill *killkrillv1.KillKrill, entitlements license.Entitlements) error {
+	targets := []struct {
+		name       string
+		deployment string
+		requested  int32
+	}{
+		{name: "killkrill-log-receiver-hpa", deployment: "killkrill-log-receiver", requested: killkrill.Spec.Applications.LogReceiver.Replicas},
+		{name: "killkrill-metrics-receiver-hpa", deployment: "killkrill-metrics-receiver", requested: killkrill.Spec.Applications.MetricsReceiver.Replicas},
+	}
+
+	for _, t := range targets {
+		minReplicas := t.requested
+		if minReplicas < 1 {
+			minReplicas = 1
+		}
+		// A cap of 0 or less means no licensed limit (see clampReplicas),
+		// so the HPA holds the requested replica count.
+		maxReplicas := entitlements.MaxReceiverReplicas
+		if maxReplicas <= 0 {
+			maxReplicas = minReplicas
+		}
+		if minReplicas > maxReplicas {
+			minReplicas = maxReplicas
+		}
+
+		utilization := int32(75)
+		hpa := &autoscalingv2.HorizontalPodAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      t.name,
+				Namespace: killkrill.Namespace,
+			},
+			Spec: autoscalingv2.HorizontalPodAutoscalerSpec{
+				ScaleTargetRef: autoscalingv2.CrossVersionObjectReference{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       t.deployment,
+				},
+				MinReplicas: &minReplicas,
+				MaxReplicas: maxReplicas,
+				Metrics: []autoscalingv2.MetricSpec{
+					{
+						Type: autoscalingv2.ResourceMetricSourceType,
+						Resource: &autoscalingv2.ResourceMetricSource{
+							Name: corev1.ResourceCPU,
+							Target: autoscalingv2.MetricTarget{
+								Type:               autoscalingv2.UtilizationMetricType,
+								AverageUtilization: &utilization,
+							},
+						},
+					},
+				},
+			},
+		}
+
+		if err := controllerutil.SetControllerReference(killkrill, hpa, r.Scheme); err != nil {
+			return fmt.Errorf("failed to set owner reference on %s: %w", t.name, err)
+		}
+
+		if err := r.Create(ctx, hpa); err != nil {
+			if !errors.IsAlreadyExists(err) {
+				return fmt.Errorf("failed to create %s: %w", t.name, err)
+			}
+			if err := r.Update(ctx, hpa); err != nil {
+				return fmt.Errorf("failed to update %s: %w", t.name, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// setCondition upserts a metav1.Condition on killkrill.Status.Conditions,
+// preserving LastTransitionTime when the condition's Status hasn't changed.
+func setCondition(killkrill *killkrillv1.KillKrill, condType string, status metav1.ConditionStatus, reason, message string) {
+	condition := metav1.Condition{
+		Type:               condType,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: killkrill.Generation,
+	}
+
+	for i, existing := range killkrill.Status.Conditions {
+		if existing.Type != condType {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		killkrill.Status.Conditions[i] = condition
+		return
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	killkrill.Status.Conditions = append(killkrill.Status.Conditions, condition)
+}
@@ -0,0 +1,142 @@
+package controllers
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+)
+
+// Component identifiers accepted by SetComponentPhase. ComponentGrafana is
+// not yet in componentSlots - see the comment on componentSlots - so
+// passing it to SetComponentPhase returns an "unknown component" error
+// until reconcileMonitoring reconciles Grafana for real.
+const (
+	ComponentLogReceiver     = "log-receiver"
+	ComponentMetricsReceiver = "metrics-receiver"
+	ComponentLogWorker       = "log-worker"
+	ComponentMetricsWorker   = "metrics-worker"
+	ComponentManager         = "manager"
+	ComponentPostgreSQL      = "postgresql"
+	ComponentRedis           = "redis"
+	ComponentElasticsearch   = "elasticsearch"
+	ComponentPrometheus      = "prometheus"
+	ComponentGrafana         = "grafana"
+)
+
+// componentSlot points SetComponentPhase at one component's field in
+// Status.ComponentStatus and the condition type it should keep in sync.
+type componentSlot struct {
+	field         *killkrillv1.Phase
+	conditionType string
+}
+
+// SetComponentPhase records phase for component in killkrill.Status, sets
+// the matching "<Component>Ready" condition, and rolls up the aggregate
+// Status.Phase from every component in componentSlots: Error wins if any
+// component reports it, then Updating, then Starting (or the zero value,
+// for a component nothing has reported yet), and only Running once every
+// tracked component is Running.
+func SetComponentPhase(killkrill *killkrillv1.KillKrill, component string, phase killkrillv1.Phase) error {
+	slots := componentSlots(&killkrill.Status)
+
+	slot, ok := slots[component]
+	if !ok {
+		return fmt.Errorf("unknown component %q", component)
+	}
+
+	*slot.field = phase
+	setCondition(killkrill, slot.conditionType, readyConditionStatus(phase), string(phase), componentPhaseMessage(component, phase))
+
+	killkrill.Status.Phase = rollupPhase(slots)
+	setAggregateConditions(killkrill)
+	return nil
+}
+
+// setAggregateConditions keeps the "Ready", "Progressing" and "Degraded"
+// conditions in sync with killkrill.Status.Phase, the same rollup
+// SetComponentPhase just computed.
+func setAggregateConditions(killkrill *killkrillv1.KillKrill) {
+	switch killkrill.Status.Phase {
+	case killkrillv1.PhaseRunning:
+		setCondition(killkrill, "Ready", metav1.ConditionTrue, "Running", "all components are running")
+		setCondition(killkrill, "Progressing", metav1.ConditionFalse, "Running", "all components are running")
+		setCondition(killkrill, "Degraded", metav1.ConditionFalse, "Running", "all components are running")
+	case killkrillv1.PhaseError:
+		setCondition(killkrill, "Ready", metav1.ConditionFalse, "Error", "one or more components are in an error state")
+		setCondition(killkrill, "Progressing", metav1.ConditionFalse, "Error", "one or more components are in an error state")
+		setCondition(killkrill, "Degraded", metav1.ConditionTrue, "Error", "one or more components are in an error state")
+	default:
+		setCondition(killkrill, "Ready", metav1.ConditionFalse, string(killkrill.Status.Phase), "one or more components are not yet running")
+		setCondition(killkrill, "Progressing", metav1.ConditionTrue, string(killkrill.Status.Phase), "one or more components are not yet running")
+		setCondition(killkrill, "Degraded", metav1.ConditionFalse, string(killkrill.Status.Phase), "one or more components are not yet running")
+	}
+}
+
+// componentSlots lists every component SetComponentPhase tracks.
+// rollupPhase's result depends only on their phases, not on the order
+// SetComponentPhase was called in.
+//
+// ComponentGrafana is deliberately not in this map: reconcileMonitoring,
+// the only place that could report it, is still a no-op stub, so nothing
+// would ever call SetComponentPhase(ComponentGrafana, ...). Since
+// rollupPhase treats a tracked-but-never-reported component as
+// permanently Starting, including it here would keep Status.Phase from
+// ever reaching Running and the Ready condition from ever going True -
+// add it back once reconcileMonitoring actually reconciles Grafana.
+func componentSlots(status *killkrillv1.KillKrillStatus) map[string]componentSlot {
+	return map[string]componentSlot{
+		ComponentLogReceiver:     {&status.ComponentStatus.LogReceiver, "LogReceiverReady"},
+		ComponentMetricsReceiver: {&status.ComponentStatus.MetricsReceiver, "MetricsReceiverReady"},
+		ComponentLogWorker:       {&status.ComponentStatus.LogWorker, "LogWorkerReady"},
+		ComponentMetricsWorker:   {&status.ComponentStatus.MetricsWorker, "MetricsWorkerReady"},
+		ComponentManager:         {&status.ComponentStatus.Manager, "ManagerReady"},
+		ComponentPostgreSQL:      {&status.ComponentStatus.Infrastructure.PostgreSQL, "PostgreSQLReady"},
+		ComponentRedis:           {&status.ComponentStatus.Infrastructure.Redis, "RedisReady"},
+		ComponentElasticsearch:   {&status.ComponentStatus.Infrastructure.Elasticsearch, "ElasticsearchReady"},
+		ComponentPrometheus:      {&status.ComponentStatus.Infrastructure.Prometheus, "PrometheusReady"},
+	}
+}
+
+// rollupPhase folds every component's phase into one aggregate phase:
+// Error beats Updating beats Starting (or unreported) beats Running.
+func rollupPhase(slots map[string]componentSlot) killkrillv1.Phase {
+	sawError, sawUpdating, sawStarting := false, false, false
+
+	for _, slot := range slots {
+		switch *slot.field {
+		case killkrillv1.PhaseError:
+			sawError = true
+		case killkrillv1.PhaseUpdating:
+			sawUpdating = true
+		case killkrillv1.PhaseRunning:
+		default:
+			sawStarting = true
+		}
+	}
+
+	switch {
+	case sawError:
+		return killkrillv1.PhaseError
+	case sawUpdating:
+		return killkrillv1.PhaseUpdating
+	case sawStarting:
+		return killkrillv1.PhaseStarting
+	default:
+		return killkrillv1.PhaseRunning
+	}
+}
+
+// readyConditionStatus maps a component's phase to its "<Component>Ready"
+// condition status: only Running counts as ready.
+func readyConditionStatus(phase killkrillv1.Phase) metav1.ConditionStatus {
+	if phase == killkrillv1.PhaseRunning {
+		return metav1.ConditionTrue
+	}
+	return metav1.ConditionFalse
+}
+
+func componentPhaseMessage(component string, phase killkrillv1.Phase) string {
+	return fmt.Sprintf("%s is %s", component, phase)
+}
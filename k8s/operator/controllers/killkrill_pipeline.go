@@ -0,0 +1,151 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+)
+
+// killkrillFinalizer is registered on every KillKrill object so the
+// reconciler gets a chance to run Spec.DeletePipeline before Kubernetes
+// garbage-collects the owned StatefulSets/Deployments/PVCs.
+const killkrillFinalizer = "killkrill.penguintech.io/finalizer"
+
+// pipelineOutcome is the result of advancing one pipeline (DeletePipeline or
+// ConfigurePipeline) by a single reconcile.
+type pipelineOutcome struct {
+	// statuses is the full, updated per-step status list, always the same
+	// length as the pipeline's step list.
+	statuses []killkrillv1.PipelineStepStatus
+
+	// done is true once every step has reported Succeeded, or Failed with
+	// ContinueOnFailure set.
+	done bool
+
+	// requeue is true when progress is still possible (a step is running,
+	// or the next one was just created) and the caller should requeue
+	// shortly instead of waiting for the next watch event.
+	requeue bool
+}
+
+// advancePipeline reconciles steps against their Jobs one at a time, in
+// order: it won't create the Job for step N+1 until step N has reported
+// Succeeded (or Failed with ContinueOnFailure). prevStatuses carries
+// forward already-observed phases across reconciles.
+func (r *KillKrillReconciler) advancePipeline(ctx context.Context, killkrill *killkrillv1.KillKrill, steps []killkrillv1.PipelineStep, prevStatuses []killkrillv1.PipelineStepStatus, namePrefix string) (pipelineOutcome, error) {
+	statuses := make([]killkrillv1.PipelineStepStatus, len(steps))
+	for i, step := range steps {
+		if i < len(prevStatuses) && prevStatuses[i].Name == step.Name {
+			statuses[i] = prevStatuses[i]
+		} else {
+			statuses[i] = killkrillv1.PipelineStepStatus{Name: step.Name, Phase: killkrillv1.PipelinePhasePending}
+		}
+	}
+
+	for i, step := range steps {
+		status := &statuses[i]
+
+		if status.Phase == killkrillv1.PipelinePhaseSucceeded {
+			continue
+		}
+		if status.Phase == killkrillv1.PipelinePhaseFailed {
+			if step.ContinueOnFailure {
+				continue
+			}
+			return pipelineOutcome{statuses: statuses}, nil
+		}
+
+		jobName := pipelineJobName(killkrill.Name, namePrefix, step.Name)
+		job := &batchv1.Job{}
+		err := r.Get(ctx, client.ObjectKey{Name: jobName, Namespace: killkrill.Namespace}, job)
+		switch {
+		case errors.IsNotFound(err):
+			newJob := buildPipelineJob(killkrill, jobName, step)
+			if err := controllerutil.SetControllerReference(killkrill, newJob, r.Scheme); err != nil {
+				return pipelineOutcome{}, fmt.Errorf("failed to set owner reference on pipeline job %s: %w", jobName, err)
+			}
+			if err := r.Create(ctx, newJob); err != nil && !errors.IsAlreadyExists(err) {
+				return pipelineOutcome{}, fmt.Errorf("failed to create pipeline job %s: %w", jobName, err)
+			}
+			status.Phase = killkrillv1.PipelinePhaseRunning
+			status.JobName = jobName
+			return pipelineOutcome{statuses: statuses, requeue: true}, nil
+
+		case err != nil:
+			return pipelineOutcome{}, fmt.Errorf("failed to get pipeline job %s: %w", jobName, err)
+
+		default:
+			status.JobName = jobName
+			switch {
+			case job.Status.Succeeded > 0:
+				status.Phase = killkrillv1.PipelinePhaseSucceeded
+				status.Message = ""
+			case job.Status.Failed > 0:
+				status.Phase = killkrillv1.PipelinePhaseFailed
+				status.Message = fmt.Sprintf("job %s reported %d failed pod(s)", jobName, job.Status.Failed)
+				if !step.ContinueOnFailure {
+					return pipelineOutcome{statuses: statuses}, nil
+				}
+			default:
+				return pipelineOutcome{statuses: statuses, requeue: true}, nil
+			}
+		}
+	}
+
+	return pipelineOutcome{statuses: statuses, done: true}, nil
+}
+
+// pipelineJobName derives a Job name from the owning KillKrill, which
+// pipeline ("delete" or "configure") and the step name.
+func pipelineJobName(killkrillName, namePrefix, stepName string) string {
+	return fmt.Sprintf("%s-%s-%s", killkrillName, namePrefix, stepName)
+}
+
+// buildPipelineJob builds the (not-yet-created) Job for a PipelineStep. It
+// never restarts on failure - advancePipeline decides whether to skip past
+// a failed step or block on it, based on step.ContinueOnFailure.
+func buildPipelineJob(killkrill *killkrillv1.KillKrill, jobName string, step killkrillv1.PipelineStep) *batchv1.Job {
+	backoffLimit := int32(0)
+
+	return &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      jobName,
+			Namespace: killkrill.Namespace,
+			Labels: map[string]string{
+				"app":                           "killkrill-pipeline",
+				"killkrill.penguintech.io/step": step.Name,
+			},
+		},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{
+					Labels: map[string]string{
+						"app": "killkrill-pipeline",
+					},
+				},
+				Spec: corev1.PodSpec{
+					RestartPolicy: corev1.RestartPolicyNever,
+					Containers: []corev1.Container{
+						{
+							Name:         step.Name,
+							Image:        step.Image,
+							Args:         step.Args,
+							Env:          step.Env,
+							VolumeMounts: step.VolumeMounts,
+						},
+					},
+					Volumes: step.Volumes,
+				},
+			},
+		},
+	}
+}
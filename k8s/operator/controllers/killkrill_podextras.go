@@ -0,0 +1,26 @@
+package controllers
+
+import (
+	corev1 "k8s.io/api/core/v1"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+)
+
+// applyPodExtras layers a component's PodExtras onto the pod spec and main
+// container a reconcile*() builder just constructed, so every generated
+// Deployment/StatefulSet supports sidecars, extra mounts and scheduling
+// constraints the same way. container must already be spec.Containers[0].
+func applyPodExtras(spec *corev1.PodSpec, container *corev1.Container, extras killkrillv1.PodExtras) {
+	container.Env = append(container.Env, extras.ExtraEnvs...)
+	container.EnvFrom = append(container.EnvFrom, extras.ExtraEnvFrom...)
+	container.VolumeMounts = append(container.VolumeMounts, extras.ExtraVolumeMounts...)
+
+	spec.Volumes = append(spec.Volumes, extras.ExtraVolumes...)
+	spec.InitContainers = append(spec.InitContainers, extras.ExtraInitContainers...)
+	spec.Containers = append(spec.Containers, extras.ExtraContainers...)
+	spec.NodeSelector = extras.NodeSelector
+	spec.Tolerations = extras.Tolerations
+	spec.Affinity = extras.Affinity
+	spec.PriorityClassName = extras.PriorityClassName
+	spec.ImagePullSecrets = extras.ImagePullSecrets
+}
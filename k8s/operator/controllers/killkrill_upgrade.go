@@ -0,0 +1,215 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+)
+
+// UpgradePauseAnnotation holds a rolling upgrade at its current phase when
+// set to "true", letting an operator inspect a partially-upgraded KillKrill
+// (or a baking canary) before Reconcile is allowed to advance it further.
+const UpgradePauseAnnotation = "killkrill.penguintech.io/upgrade-paused"
+
+// upgradePhases is the fixed order Spec.UpgradeStrategy moves through: data
+// stores one at a time - Elasticsearch data nodes respecting cluster green
+// state, PostgreSQL primary last among the stores since workers and
+// receivers depend on it staying reachable throughout - then workers, then
+// receivers.
+var upgradePhases = []string{
+	killkrillv1.UpgradePhaseElasticsearch,
+	killkrillv1.UpgradePhasePostgreSQL,
+	killkrillv1.UpgradePhaseWorkers,
+	killkrillv1.UpgradePhaseReceivers,
+}
+
+// advanceUpgrade drives Status.Upgrade through upgradePhases, one phase per
+// reconcile once that phase's health gate reports ready. It is a no-op when
+// Spec.UpgradeStrategy is disabled, so the rest of Reconcile proceeds
+// exactly as it did before this orchestration existed.
+//
+// done is true once the upgrade has either finished or isn't running, and
+// requeueAfter is how long to wait before checking the gate again when it's
+// not.
+func (r *KillKrillReconciler) advanceUpgrade(ctx context.Context, killkrill *killkrillv1.KillKrill) (done bool, requeueAfter time.Duration, err error) {
+	if !killkrill.Spec.UpgradeStrategy.Enabled {
+		return true, 0, nil
+	}
+
+	if killkrill.Annotations[UpgradePauseAnnotation] == "true" {
+		killkrill.Status.Upgrade.Paused = true
+		killkrill.Status.Upgrade.Message = fmt.Sprintf("upgrade paused by %s annotation", UpgradePauseAnnotation)
+		return false, time.Minute, nil
+	}
+	killkrill.Status.Upgrade.Paused = false
+
+	phase := killkrill.Status.Upgrade.Phase
+	if phase == "" || phase == killkrillv1.UpgradePhaseComplete {
+		phase = upgradePhases[0]
+	}
+
+	if killkrill.Spec.UpgradeStrategy.Canary.Enabled && phase == upgradePhases[0] && !killkrill.Status.Upgrade.CanaryActive {
+		killkrill.Status.Upgrade.Phase = phase
+		killkrill.Status.Upgrade.CurrentComponent = phase
+		killkrill.Status.Upgrade.CanaryActive = true
+		killkrill.Status.Upgrade.Message = "canary bake period started before the first phase's health gate"
+		return false, canaryBakeDuration(killkrill.Spec.UpgradeStrategy.Canary), nil
+	}
+
+	healthy, message, err := r.upgradePhaseHealthy(ctx, killkrill, phase)
+	if err != nil {
+		return false, time.Minute, fmt.Errorf("failed to check %s health during upgrade: %w", phase, err)
+	}
+
+	killkrill.Status.Upgrade.Phase = phase
+	killkrill.Status.Upgrade.CurrentComponent = phase
+	killkrill.Status.Upgrade.Message = message
+
+	if !healthy {
+		return false, 15 * time.Second, nil
+	}
+
+	next := nextUpgradePhase(phase)
+	if next == "" {
+		killkrill.Status.Upgrade.Phase = killkrillv1.UpgradePhaseComplete
+		killkrill.Status.Upgrade.CurrentComponent = ""
+		killkrill.Status.Upgrade.CanaryActive = false
+		killkrill.Status.Upgrade.Message = "upgrade complete"
+		return true, 0, nil
+	}
+
+	killkrill.Status.Upgrade.Phase = next
+	killkrill.Status.Upgrade.CanaryActive = false
+	return false, 0, nil
+}
+
+// nextUpgradePhase returns the phase after current, or "" once current is
+// the last one in upgradePhases.
+func nextUpgradePhase(current string) string {
+	for i, phase := range upgradePhases {
+		if phase == current && i+1 < len(upgradePhases) {
+			return upgradePhases[i+1]
+		}
+	}
+	return ""
+}
+
+// canaryBakeDuration parses Canary.BakeTime, defaulting to 10 minutes on an
+// empty or invalid value so a misconfigured duration doesn't leave the
+// canary phase stuck forever.
+func canaryBakeDuration(canary killkrillv1.CanaryConfig) time.Duration {
+	d, err := time.ParseDuration(canary.BakeTime)
+	if err != nil {
+		return 10 * time.Minute
+	}
+	return d
+}
+
+// upgradePhaseHealthy runs the health gate for phase against the
+// corresponding component's in-cluster Service.
+func (r *KillKrillReconciler) upgradePhaseHealthy(ctx context.Context, killkrill *killkrillv1.KillKrill, phase string) (bool, string, error) {
+	switch phase {
+	case killkrillv1.UpgradePhaseElasticsearch:
+		return checkElasticsearchHealth(ctx, killkrill.Namespace)
+	case killkrillv1.UpgradePhasePostgreSQL:
+		pgReady, pgMessage, err := checkPostgresReady(ctx, killkrill.Namespace)
+		if err != nil || !pgReady {
+			return pgReady, pgMessage, err
+		}
+		// Redis is upgraded alongside PostgreSQL in this phase, rather
+		// than getting a phase of its own: both are in place before
+		// workers start, and neither blocks the other's availability.
+		return checkRedisReplicationHealthy(ctx, killkrill.Namespace)
+	case killkrillv1.UpgradePhaseWorkers, killkrillv1.UpgradePhaseReceivers:
+		// Workers and receivers have no external health store to poll;
+		// readiness already flows from Status.ResourceBundle once the
+		// status aggregator has observed their rollout.
+		ready := killkrill.Status.ResourceBundle.Ready == killkrillv1.ReadyStateReady
+		return ready, fmt.Sprintf("resource bundle is %s", killkrill.Status.ResourceBundle.Ready), nil
+	default:
+		return false, fmt.Sprintf("unknown upgrade phase %q", phase), nil
+	}
+}
+
+// checkElasticsearchHealth polls the cluster's own _cluster/health endpoint
+// and only reports ready once status is "green", matching the request that
+// Elasticsearch data nodes upgrade one at a time respecting cluster green
+// state.
+func checkElasticsearchHealth(ctx context.Context, namespace string) (bool, string, error) {
+	url := fmt.Sprintf("http://killkrill-elasticsearch.%s.svc.cluster.local:9200/_cluster/health", namespace)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return false, "", fmt.Errorf("failed to build elasticsearch health request: %w", err)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		return false, fmt.Sprintf("elasticsearch cluster health unreachable: %v", err), nil
+	}
+	defer resp.Body.Close()
+
+	var body struct {
+		Status string `json:"status"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return false, fmt.Sprintf("failed to decode elasticsearch cluster health response: %v", err), nil
+	}
+
+	return body.Status == "green", fmt.Sprintf("elasticsearch cluster status is %s", body.Status), nil
+}
+
+// checkPostgresReady and checkRedisReplicationHealthy dial the component's port as
+// a reachability proxy for pg_isready/INFO replication: those commands run
+// inside the target pod, and running them from the controller would need
+// the remotecommand exec API rather than a plain client-go Get/List, which
+// is a larger follow-up than this phase gate needs to block on.
+func checkPostgresReady(ctx context.Context, namespace string) (bool, string, error) {
+	return dialReachable(ctx, fmt.Sprintf("killkrill-postgres.%s.svc.cluster.local:5432", namespace), "postgresql")
+}
+
+func checkRedisReplicationHealthy(ctx context.Context, namespace string) (bool, string, error) {
+	return dialReachable(ctx, fmt.Sprintf("killkrill-redis.%s.svc.cluster.local:6379", namespace), "redis")
+}
+
+// dialReachable reports whether addr accepts a TCP connection within a
+// short timeout.
+func dialReachable(ctx context.Context, addr, component string) (bool, string, error) {
+	dialer := net.Dialer{Timeout: 5 * time.Second}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return false, fmt.Sprintf("%s at %s is unreachable: %v", component, addr, err), nil
+	}
+	conn.Close()
+	return true, fmt.Sprintf("%s at %s is reachable", component, addr), nil
+}
+
+// componentRolloutStrategy builds component's Deployment rollout strategy
+// from Spec.UpgradeStrategy.ComponentOverrides, falling back to Kubernetes'
+// own RollingUpdate defaults when component isn't listed there. component
+// is the ComponentOverrides map key (e.g. "logReceiver"), not one of the
+// kebab-case Component* constants in killkrill_phase.go - only a component
+// whose reconcile function actually builds a Deployment has a strategy for
+// this to apply to.
+func componentRolloutStrategy(killkrill *killkrillv1.KillKrill, component string) appsv1.DeploymentStrategy {
+	override, ok := killkrill.Spec.UpgradeStrategy.ComponentOverrides[component]
+	if !ok {
+		return appsv1.DeploymentStrategy{Type: appsv1.RollingUpdateDeploymentStrategyType}
+	}
+
+	return appsv1.DeploymentStrategy{
+		Type: appsv1.RollingUpdateDeploymentStrategyType,
+		RollingUpdate: &appsv1.RollingUpdateDeployment{
+			MaxSurge:       override.MaxSurge,
+			MaxUnavailable: override.MaxUnavailable,
+		},
+	}
+}
@@ -0,0 +1,98 @@
+// Package license validates a KillKrill's Spec.License.Key against the
+// PenguinTech license server and caches the resulting entitlements for a
+// bounded TTL, so reconciling the same KillKrill repeatedly doesn't make a
+// network round trip every time.
+package license
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// DefaultServer is used when a KillKrill's Spec.License.Server is empty.
+const DefaultServer = "https://license.penguintech.io"
+
+// Entitlements is what a license key is allowed. KillKrillReconciler uses
+// these both to size the receiver HPAs and as the hard cap it refuses to
+// let the receiver and worker Replicas in Spec.Applications exceed.
+type Entitlements struct {
+	MaxEPS              int   `json:"maxEps"`
+	MaxIngestGBPerDay   int   `json:"maxIngestGbPerDay"`
+	MaxRetainedTenants  int   `json:"maxRetainedTenants"`
+	MaxReceiverReplicas int32 `json:"maxReceiverReplicas"`
+	MaxWorkerReplicas   int32 `json:"maxWorkerReplicas"`
+}
+
+// Client validates license keys against a license server, caching each
+// key's entitlements for TTL.
+type Client struct {
+	HTTPClient *http.Client
+	TTL        time.Duration
+
+	mu    sync.Mutex
+	cache map[string]cacheEntry
+}
+
+type cacheEntry struct {
+	entitlements Entitlements
+	expiresAt    time.Time
+}
+
+// NewClient builds a Client with sane defaults for the HTTP client timeout
+// and cache TTL.
+func NewClient() *Client {
+	return &Client{
+		HTTPClient: &http.Client{Timeout: 10 * time.Second},
+		TTL:        5 * time.Minute,
+		cache:      make(map[string]cacheEntry),
+	}
+}
+
+// Validate returns key's entitlements, serving a cached value when one
+// exists and hasn't expired, and otherwise validating against server (or
+// DefaultServer, if empty) and caching the result for c.TTL.
+func (c *Client) Validate(ctx context.Context, server, product, key string) (Entitlements, error) {
+	c.mu.Lock()
+	entry, ok := c.cache[key]
+	c.mu.Unlock()
+	if ok && time.Now().Before(entry.expiresAt) {
+		return entry.entitlements, nil
+	}
+
+	if server == "" {
+		server = DefaultServer
+	}
+
+	reqURL := fmt.Sprintf("%s/api/v1/licenses/%s/validate", server, url.PathEscape(key))
+	query := url.Values{"product": {product}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return Entitlements{}, fmt.Errorf("failed to build license validation request: %w", err)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return Entitlements{}, fmt.Errorf("failed to reach license server: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Entitlements{}, fmt.Errorf("license server rejected key: status %d", resp.StatusCode)
+	}
+
+	var entitlements Entitlements
+	if err := json.NewDecoder(resp.Body).Decode(&entitlements); err != nil {
+		return Entitlements{}, fmt.Errorf("failed to decode license entitlements: %w", err)
+	}
+
+	c.mu.Lock()
+	c.cache[key] = cacheEntry{entitlements: entitlements, expiresAt: time.Now().Add(c.TTL)}
+	c.mu.Unlock()
+
+	return entitlements, nil
+}
@@ -0,0 +1,308 @@
+// Package status watches the resources a KillKrill owns (Deployments,
+// StatefulSets, Services, PersistentVolumeClaims, Ingresses, ConfigMaps and
+// Jobs) and rolls their observed state up into KillKrill.Status.ResourceBundle,
+// so the main KillKrillReconciler can react to a crashloop or a pending PVC
+// within seconds instead of waiting for its next poll.
+package status
+
+import (
+	"context"
+	"fmt"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	killkrillv1 "github.com/penguintechinc/killkrill/api/v1"
+)
+
+// Aggregator recomputes a KillKrill's ResourceBundle from its currently
+// owned resources and patches Status. It holds no state of its own beyond
+// the client, so a single Aggregator is shared by every per-kind
+// Reconciler registered in SetupAll.
+type Aggregator struct {
+	client.Client
+}
+
+// Refresh lists every resource kind the status subsystem watches, keeps the
+// ones owned by the KillKrill named by key, and writes the resulting
+// ResourceBundle (and Ready condition) back if anything changed.
+func (a *Aggregator) Refresh(ctx context.Context, key types.NamespacedName) error {
+	killkrill := &killkrillv1.KillKrill{}
+	if err := a.Get(ctx, key, killkrill); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	prev := make(map[string]killkrillv1.OwnedResourceStatus, len(killkrill.Status.ResourceBundle.Resources))
+	for _, r := range killkrill.Status.ResourceBundle.Resources {
+		prev[r.Kind+"/"+r.Name] = r
+	}
+
+	resources, degraded, err := a.collect(ctx, key.Namespace, killkrill.UID, prev)
+	if err != nil {
+		return err
+	}
+
+	bundle := killkrillv1.ResourceBundle{Resources: resources, Ready: aggregateReadyState(resources, degraded)}
+	if resourceBundleEqual(killkrill.Status.ResourceBundle, bundle) {
+		return nil
+	}
+
+	killkrill.Status.ResourceBundle = bundle
+	setReadyCondition(killkrill, bundle.Ready)
+
+	if err := a.Status().Update(ctx, killkrill); err != nil {
+		return fmt.Errorf("failed to update killkrill resource bundle status: %w", err)
+	}
+
+	return nil
+}
+
+// collect lists every watched kind in namespace, filters to the ones owned
+// by ownerUID, and turns each into an OwnedResourceStatus. It also reports
+// whether any resource shows a stalled rollout, a lost volume or a failed
+// Job, which
+// aggregateReadyState treats as Degraded rather than merely Progressing.
+func (a *Aggregator) collect(ctx context.Context, namespace string, ownerUID types.UID, prev map[string]killkrillv1.OwnedResourceStatus) ([]killkrillv1.OwnedResourceStatus, bool, error) {
+	var resources []killkrillv1.OwnedResourceStatus
+	degraded := false
+
+	deployments := &appsv1.DeploymentList{}
+	if err := a.List(ctx, deployments, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	for i := range deployments.Items {
+		d := &deployments.Items[i]
+		if !isOwnedBy(d, ownerUID) {
+			continue
+		}
+		replicas := int32(1)
+		if d.Spec.Replicas != nil {
+			replicas = *d.Spec.Replicas
+		}
+		ready := d.Status.ReadyReplicas >= replicas
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:          d.Name,
+			Kind:          "Deployment",
+			Ready:         ready,
+			Replicas:      replicas,
+			ReadyReplicas: d.Status.ReadyReplicas,
+		}))
+		if !ready && deploymentRolloutStalled(d) {
+			degraded = true
+		}
+	}
+
+	statefulSets := &appsv1.StatefulSetList{}
+	if err := a.List(ctx, statefulSets, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list statefulsets: %w", err)
+	}
+	for i := range statefulSets.Items {
+		s := &statefulSets.Items[i]
+		if !isOwnedBy(s, ownerUID) {
+			continue
+		}
+		replicas := int32(1)
+		if s.Spec.Replicas != nil {
+			replicas = *s.Spec.Replicas
+		}
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:          s.Name,
+			Kind:          "StatefulSet",
+			Ready:         s.Status.ReadyReplicas >= replicas,
+			Replicas:      replicas,
+			ReadyReplicas: s.Status.ReadyReplicas,
+		}))
+	}
+
+	services := &corev1.ServiceList{}
+	if err := a.List(ctx, services, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list services: %w", err)
+	}
+	for i := range services.Items {
+		svc := &services.Items[i]
+		if !isOwnedBy(svc, ownerUID) {
+			continue
+		}
+		// A Service has no rollout of its own; it's ready as soon as it exists.
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:  svc.Name,
+			Kind:  "Service",
+			Ready: true,
+		}))
+	}
+
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := a.List(ctx, pvcs, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list persistentvolumeclaims: %w", err)
+	}
+	for i := range pvcs.Items {
+		p := &pvcs.Items[i]
+		if !isOwnedBy(p, ownerUID) {
+			continue
+		}
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:     p.Name,
+			Kind:     "PersistentVolumeClaim",
+			Ready:    p.Status.Phase == corev1.ClaimBound,
+			PVCPhase: string(p.Status.Phase),
+		}))
+		if p.Status.Phase == corev1.ClaimLost {
+			degraded = true
+		}
+	}
+
+	ingresses := &networkingv1.IngressList{}
+	if err := a.List(ctx, ingresses, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list ingresses: %w", err)
+	}
+	for i := range ingresses.Items {
+		ing := &ingresses.Items[i]
+		if !isOwnedBy(ing, ownerUID) {
+			continue
+		}
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:  ing.Name,
+			Kind:  "Ingress",
+			Ready: len(ing.Status.LoadBalancer.Ingress) > 0,
+		}))
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := a.List(ctx, configMaps, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list configmaps: %w", err)
+	}
+	for i := range configMaps.Items {
+		cm := &configMaps.Items[i]
+		if !isOwnedBy(cm, ownerUID) {
+			continue
+		}
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:  cm.Name,
+			Kind:  "ConfigMap",
+			Ready: true,
+		}))
+	}
+
+	jobs := &batchv1.JobList{}
+	if err := a.List(ctx, jobs, client.InNamespace(namespace)); err != nil {
+		return nil, false, fmt.Errorf("failed to list jobs: %w", err)
+	}
+	for i := range jobs.Items {
+		j := &jobs.Items[i]
+		if !isOwnedBy(j, ownerUID) {
+			continue
+		}
+		ready := j.Status.Succeeded > 0
+		resources = append(resources, withTransitionTime(prev, killkrillv1.OwnedResourceStatus{
+			Name:  j.Name,
+			Kind:  "Job",
+			Ready: ready,
+		}))
+		if !ready && j.Status.Failed > 0 && j.Status.Active == 0 {
+			degraded = true
+		}
+	}
+
+	return resources, degraded, nil
+}
+
+// isOwnedBy reports whether obj's controller owner reference matches ownerUID.
+func isOwnedBy(obj client.Object, ownerUID types.UID) bool {
+	ref := metav1.GetControllerOf(obj)
+	return ref != nil && ref.UID == ownerUID
+}
+
+// deploymentRolloutStalled mirrors kubectl's own rollout-status check: a
+// Deployment whose "Progressing" condition has gone False (reason
+// ProgressDeadlineExceeded) is crashlooping or otherwise stuck, not merely
+// still rolling out.
+func deploymentRolloutStalled(d *appsv1.Deployment) bool {
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Status == corev1.ConditionFalse {
+			return true
+		}
+	}
+	return false
+}
+
+// withTransitionTime keeps a resource's previous LastTransitionTime when its
+// Ready value hasn't changed since the last Refresh, and otherwise stamps a
+// fresh one, taken per resource with metav1.Now().
+func withTransitionTime(prev map[string]killkrillv1.OwnedResourceStatus, r killkrillv1.OwnedResourceStatus) killkrillv1.OwnedResourceStatus {
+	if p, ok := prev[r.Kind+"/"+r.Name]; ok && p.Ready == r.Ready {
+		r.LastTransitionTime = p.LastTransitionTime
+		return r
+	}
+	r.LastTransitionTime = metav1.Now()
+	return r
+}
+
+// aggregateReadyState folds the per-resource Ready flags (and the Degraded
+// signal collect surfaced separately) into one top-level state.
+func aggregateReadyState(resources []killkrillv1.OwnedResourceStatus, degraded bool) string {
+	if degraded {
+		return killkrillv1.ReadyStateDegraded
+	}
+	for _, r := range resources {
+		if !r.Ready {
+			return killkrillv1.ReadyStateProgressing
+		}
+	}
+	return killkrillv1.ReadyStateReady
+}
+
+// resourceBundleEqual compares everything except LastTransitionTime, which
+// withTransitionTime already keeps stable across no-op refreshes; comparing
+// it here would make two bundles built a few nanoseconds apart never equal.
+func resourceBundleEqual(a, b killkrillv1.ResourceBundle) bool {
+	if a.Ready != b.Ready || len(a.Resources) != len(b.Resources) {
+		return false
+	}
+	for i := range a.Resources {
+		x, y := a.Resources[i], b.Resources[i]
+		if x.Name != y.Name || x.Kind != y.Kind || x.Ready != y.Ready ||
+			x.Replicas != y.Replicas || x.ReadyReplicas != y.ReadyReplicas || x.PVCPhase != y.PVCPhase {
+			return false
+		}
+	}
+	return true
+}
+
+// setReadyCondition mirrors bundleReady into Status.Conditions' "Ready"
+// condition, which is what kubectl wait and other condition-aware tooling
+// look at instead of the KillKrill-specific ResourceBundle.Ready string.
+func setReadyCondition(killkrill *killkrillv1.KillKrill, bundleReady string) {
+	condStatus := metav1.ConditionFalse
+	if bundleReady == killkrillv1.ReadyStateReady {
+		condStatus = metav1.ConditionTrue
+	}
+
+	condition := metav1.Condition{
+		Type:               "Ready",
+		Status:             condStatus,
+		Reason:             bundleReady,
+		Message:            fmt.Sprintf("resource bundle is %s", bundleReady),
+		ObservedGeneration: killkrill.Generation,
+	}
+
+	for i, existing := range killkrill.Status.Conditions {
+		if existing.Type != "Ready" {
+			continue
+		}
+		if existing.Status == condition.Status {
+			condition.LastTransitionTime = existing.LastTransitionTime
+		} else {
+			condition.LastTransitionTime = metav1.Now()
+		}
+		killkrill.Status.Conditions[i] = condition
+		return
+	}
+
+	condition.LastTransitionTime = metav1.Now()
+	killkrill.Status.Conditions = append(killkrill.Status.Conditions, condition)
+}
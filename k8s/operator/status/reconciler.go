@@ -0,0 +1,110 @@
+package status
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+)
+
+// Reconciler refreshes the owning KillKrill's ResourceBundle whenever one of
+// its owned resources changes. newObject builds an empty instance of the
+// watched kind; one Reconciler is registered per kind in SetupAll.
+type Reconciler struct {
+	Aggregator
+	kind      string
+	newObject func() client.Object
+}
+
+// Reconcile looks up the object that triggered the event, resolves its
+// controller owner, and - if that owner is a KillKrill - recomputes the
+// whole bundle for it. The object itself is otherwise unused: Aggregator.
+// Refresh re-lists every watched kind rather than patching in just this one,
+// since a bundle is only ever meaningful as a whole.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	obj := r.newObject()
+	if err := r.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	owner := metav1.GetControllerOf(obj)
+	if owner == nil || owner.Kind != "KillKrill" {
+		return ctrl.Result{}, nil
+	}
+
+	if err := r.Refresh(ctx, types.NamespacedName{Name: owner.Name, Namespace: req.Namespace}); err != nil {
+		if errors.IsConflict(err) {
+			return ctrl.Result{Requeue: true}, nil
+		}
+		return ctrl.Result{}, err
+	}
+
+	return ctrl.Result{}, nil
+}
+
+// SetupWithManager registers this Reconciler against its own kind, filtered
+// by StatusRelevant so spec changes (a Generation bump) don't trigger a
+// refresh; metadata-only updates still do.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("killkrill-status-"+r.kind).
+		For(r.newObject(), builder.WithPredicates(StatusRelevant)).
+		Complete(r)
+}
+
+// SetupAll registers one Reconciler per watched kind (Deployment,
+// StatefulSet, Service, PersistentVolumeClaim, Ingress, ConfigMap, Job)
+// against mgr, replacing KillKrillReconciler's old Owns()-driven polling
+// with watchers that fire the moment a owned resource's status changes.
+func SetupAll(mgr ctrl.Manager) error {
+	aggregator := Aggregator{Client: mgr.GetClient()}
+
+	reconcilers := []*Reconciler{
+		{Aggregator: aggregator, kind: "deployment", newObject: func() client.Object { return &appsv1.Deployment{} }},
+		{Aggregator: aggregator, kind: "statefulset", newObject: func() client.Object { return &appsv1.StatefulSet{} }},
+		{Aggregator: aggregator, kind: "service", newObject: func() client.Object { return &corev1.Service{} }},
+		{Aggregator: aggregator, kind: "persistentvolumeclaim", newObject: func() client.Object { return &corev1.PersistentVolumeClaim{} }},
+		{Aggregator: aggregator, kind: "ingress", newObject: func() client.Object { return &networkingv1.Ingress{} }},
+		{Aggregator: aggregator, kind: "configmap", newObject: func() client.Object { return &corev1.ConfigMap{} }},
+		{Aggregator: aggregator, kind: "job", newObject: func() client.Object { return &batchv1.Job{} }},
+	}
+
+	for _, rec := range reconcilers {
+		if err := rec.SetupWithManager(mgr); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// StatusRelevant lets create/delete events through unconditionally and
+// drops updates that changed an object's spec: objects with
+// status-subresource semantics (Deployment, StatefulSet, Job, ...) only
+// bump Generation on a spec change, so an unchanged Generation means the
+// update was to Status or to metadata/annotations, and both kinds of update
+// are let through. Kinds with no Generation semantics of their own
+// (ConfigMap, Service) report Generation 0 on both sides and are let
+// through every time - they're cheap to re-aggregate and rarely change.
+var StatusRelevant = predicate.Funcs{
+	CreateFunc: func(event.CreateEvent) bool { return true },
+	DeleteFunc: func(event.DeleteEvent) bool { return true },
+	UpdateFunc: func(e event.UpdateEvent) bool {
+		oldGen, newGen := e.ObjectOld.GetGeneration(), e.ObjectNew.GetGeneration()
+		if oldGen == 0 && newGen == 0 {
+			return true
+		}
+		return oldGen == newGen
+	},
+	GenericFunc: func(event.GenericEvent) bool { return false },
+}